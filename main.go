@@ -46,6 +46,16 @@ func diagonalSudoku() sudoku.Board {
 	return board
 }
 
+// boardPrinter prints the board on every decision, showing the progress of a search.
+type boardPrinter struct {
+	sat.NoopObserver
+}
+
+func (boardPrinter) OnDecision(literal sat.Literal, state sat.SearchState) {
+	fmt.Println(conversion.ParseState(state.Assignment()).String() + "\n")
+	fmt.Println(literal.Name())
+}
+
 func solverTest(board sudoku.Board) {
 	fmt.Println("Input:")
 	fmt.Println(board)
@@ -54,14 +64,13 @@ func solverTest(board sudoku.Board) {
 
 	fmt.Println("\nSolving...")
 	start := time.Now()
-	results, ok := sat.Solve(formula, make(map[string]bool), func(state map[string]bool) string {
-		return conversion.ParseState(state).String() + "\n"
-	})
+	result := sat.SolveWithOptions(formula, make(map[string]bool), sat.Options{Observer: boardPrinter{}})
 	duration := time.Since(start)
 
-	board = conversion.ParseState(results)
-	fmt.Println("\nResult:", ok, duration)
+	board = conversion.ParseState(result.Model)
+	fmt.Println("\nResult:", result.Status, duration)
 	fmt.Println(board)
+	fmt.Println(result.Stats)
 }
 
 func main() {
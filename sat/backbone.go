@@ -0,0 +1,61 @@
+package sat
+
+// Backbone finds the literals that are true in every model of a formula, called its backbone.
+// Only the named variables are considered, or every variable if none are named. As in SolveAll, names missing from the
+// formula are ignored. Returns the value each variable in the backbone takes in every model, and Satisfiable.
+// If the formula has no models, returns Unsatisfiable and no backbone. If a search is stopped by its options, returns
+// Unknown and no backbone.
+//
+// A first model gives the candidates. Each remaining candidate is tested by searching for a model in which it is
+// false. Either there is none, so the candidate is in the backbone and is added as a unit clause for later searches,
+// or the model found rules out every candidate it disagrees with.
+func Backbone(formula ConjunctiveFormula, options Options, names ...string) (map[string]bool, Status) {
+	s := NewSolver(options)
+	s.AddFormula(formula)
+
+	result := s.Solve()
+	if result.Status != Satisfiable {
+		return nil, result.Status
+	}
+
+	if len(names) == 0 {
+		for v := 0; v < s.s.variables.Len(); v++ {
+			names = append(names, s.s.variables.Name(Var(v)))
+		}
+	}
+	candidates := make(map[string]bool)
+	order := make([]string, 0, len(names))
+	for _, name := range names {
+		if value, ok := result.Model[name]; ok {
+			candidates[name] = value
+			order = append(order, name)
+		}
+	}
+
+	backbone := make(map[string]bool)
+	for _, name := range order {
+		value, ok := candidates[name]
+		if !ok {
+			continue
+		}
+
+		var literal Literal = NewLiteral(name)
+		if !value {
+			literal = literal.Negate()
+		}
+		switch result := s.Solve(literal.Negate()); result.Status {
+		case Unsatisfiable:
+			backbone[name] = value
+			s.AddClause(NewDisjunctiveClause(literal))
+		case Satisfiable:
+			for candidate, candidateValue := range candidates {
+				if result.Model[candidate] != candidateValue {
+					delete(candidates, candidate)
+				}
+			}
+		default:
+			return nil, Unknown
+		}
+	}
+	return backbone, Satisfiable
+}
@@ -0,0 +1,186 @@
+package sat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CardinalityConstraint asserts that the number of its literals that are true is within bounds.
+// Literals are counted once per occurrence, and must be plain.
+//
+// The solver propagates cardinality constraints directly, which takes far fewer clauses than spelling them out:
+// at most one of n literals takes n(n-1)/2 binary clauses. ToCNF expands them into clauses where pure CNF is needed.
+type CardinalityConstraint struct {
+	literals []Literal
+	min, max int
+}
+
+// AtMost creates a constraint that at most k of the given literals are true.
+func AtMost(k int, literals ...Literal) CardinalityConstraint {
+	return CardinalityConstraint{literals, 0, k}
+}
+
+// AtLeast creates a constraint that at least k of the given literals are true.
+func AtLeast(k int, literals ...Literal) CardinalityConstraint {
+	return CardinalityConstraint{literals, k, len(literals)}
+}
+
+// Exactly creates a constraint that exactly k of the given literals are true.
+func Exactly(k int, literals ...Literal) CardinalityConstraint {
+	return CardinalityConstraint{literals, k, k}
+}
+
+// Literals returns the literals counted by this constraint.
+func (c CardinalityConstraint) Literals() []Literal {
+	return c.literals
+}
+
+// Bounds returns the fewest and most literals that may be true.
+func (c CardinalityConstraint) Bounds() (min, max int) {
+	return c.min, c.max
+}
+
+// Evaluate evaluates this constraint, returning a simplified constraint or a bool.
+func (c CardinalityConstraint) Evaluate(state map[string]bool) interface{} {
+	remaining := make([]Literal, 0, len(c.literals))
+	count := 0
+	for _, literal := range c.literals {
+		switch value := literal.Evaluate(state).(type) {
+		case bool:
+			if value {
+				count++
+			}
+		case Literal:
+			remaining = append(remaining, value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+
+	min, max := c.min-count, c.max-count
+	if max < 0 || min > len(remaining) {
+		return false
+	}
+	if min <= 0 && max >= len(remaining) {
+		return true
+	}
+	if min < 0 {
+		min = 0
+	}
+	if max > len(remaining) {
+		max = len(remaining)
+	}
+	return CardinalityConstraint{remaining, min, max}
+}
+
+// ToFormula returns a formula containing this constraint.
+func (c CardinalityConstraint) ToFormula() ConjunctiveFormula {
+	return ConjunctiveFormula{cardinalities: []CardinalityConstraint{c}}
+}
+
+func (c CardinalityConstraint) String() string {
+	strs := make([]string, 0, len(c.literals))
+	for _, literal := range c.literals {
+		strs = append(strs, fmt.Sprintf("%s", literal))
+	}
+	literals := strings.Join(strs, ", ")
+
+	switch {
+	case c.min == c.max:
+		return fmt.Sprintf("exactly %d of %s", c.min, literals)
+	case c.min <= 0:
+		return fmt.Sprintf("at most %d of %s", c.max, literals)
+	case c.max >= len(c.literals):
+		return fmt.Sprintf("at least %d of %s", c.min, literals)
+	default:
+		return fmt.Sprintf("between %d and %d of %s", c.min, c.max, literals)
+	}
+}
+
+// cardinality is a cardinality constraint as seen by the solver: at most max of its literals may be true.
+// A lower bound is an upper bound on the negated literals, so every constraint is kept in this form.
+// The solver counts the true literals as they are assigned. Once the count reaches the maximum, every other literal
+// is implied false.
+type cardinality struct {
+	lits  []Lit
+	max   int
+	count int // The number of lits currently true, counting repeats.
+}
+
+// addCardinality adds an original cardinality constraint to the solver. The solver must be at the root level.
+func (s *solver) addCardinality(c CardinalityConstraint) {
+	lits := make([]Lit, 0, len(c.literals))
+	negated := make([]Lit, 0, len(c.literals))
+	for _, literal := range c.literals {
+		l := s.variables.Lit(literal)
+		lits = append(lits, l)
+		negated = append(negated, l.Not())
+	}
+
+	s.addAtMost(lits, c.max)
+	s.addAtMost(negated, len(lits)-c.min)
+}
+
+// addAtMost adds a constraint that at most max of the given literals are true.
+func (s *solver) addAtMost(lits []Lit, max int) {
+	if max < 0 {
+		s.ok = false
+		return
+	}
+	if max >= len(lits) {
+		return
+	}
+
+	c := &cardinality{lits: lits, max: max}
+	for _, l := range lits {
+		if s.assigns.Value(l) == LTrue {
+			c.count++
+		}
+		s.cardinalityWatches[l] = append(s.cardinalityWatches[l], c)
+	}
+	s.cardinalities = append(s.cardinalities, c)
+}
+
+// propagateCardinality implies every unassigned literal of a cardinality constraint false, if the constraint has
+// reached its maximum. Returns a conflicting clause if the constraint is past its maximum.
+func (s *solver) propagateCardinality(c *cardinality) *clause {
+	if c.count < c.max {
+		return nil
+	}
+	if c.count > c.max {
+		return s.explainCardinality(c, nil)
+	}
+
+	for _, l := range c.lits {
+		if s.assigns.Value(l) == LUndef {
+			implied := l.Not()
+			s.enqueue(implied, s.explainCardinality(c, &implied))
+		}
+	}
+	return nil
+}
+
+// explainCardinality returns a reason clause for a cardinality constraint implying a literal, or for it conflicting
+// if implied is nil: the implied literal, followed by the negations of enough true literals to reach the maximum, or
+// to pass it for a conflict.
+func (s *solver) explainCardinality(c *cardinality, implied *Lit) *clause {
+	needed := c.max
+	if implied == nil {
+		needed++
+	}
+
+	lits := make([]Lit, 0, needed+1)
+	if implied != nil {
+		lits = append(lits, *implied)
+	}
+	for _, l := range c.lits {
+		if needed == 0 {
+			break
+		}
+		if s.assigns.Value(l) == LTrue {
+			lits = append(lits, l.Not())
+			needed--
+		}
+	}
+	return &clause{lits: lits}
+}
@@ -0,0 +1,895 @@
+package sat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// clause is a disjunctive clause of plain literals, as seen by the solver.
+// The first two literals are watched. For an implied assignment, the first literal is the one implied.
+type clause struct {
+	lits     []Lit
+	learnt   bool    // Whether this clause was learned from a conflict.
+	lbd      int     // The literal block distance of a learned clause.
+	activity float64 // How recently and often a learned clause took part in conflict analysis.
+}
+
+// compositeClause is a disjunctive clause containing composite literals.
+// Composites can only be evaluated, so the solver re-inspects the clause whenever one of its variables is assigned.
+type compositeClause struct {
+	lits       []Lit     // The plain literals in the clause.
+	composites []Literal // The composite literals in the clause.
+	vars       []Var     // The variables mentioned by the composites.
+}
+
+type clauseStatus int
+
+const (
+	unresolved clauseStatus = iota
+	satisfied
+	unit
+	conflicting
+)
+
+// solver is a conflict-driven clause learning (CDCL) solver.
+//
+// Every assignment is recorded on a trail along with its decision level and, for implied assignments, the clause
+// that forced it. Together these form the implication graph. Conflicts are analyzed back to the first unique
+// implication point, the resulting clause is learned, and the search jumps back to the highest level at which the
+// learned clause is unit.
+//
+// Unit propagation uses two watched literals per clause: a clause only needs to be inspected when one of its
+// watched literals becomes false, and watches don't need to be restored on backtracking.
+type solver struct {
+	variables            *VariableTable
+	clauses              []*clause
+	learnts              []*clause
+	composites           []*compositeClause
+	cardinalities        []*cardinality
+	linears              []*linear
+	xors                 []*xorRow
+	watches              [][]*clause          // The clauses to inspect when each literal becomes true.
+	compositeOccurrences [][]*compositeClause // The composite clauses mentioning each variable.
+	cardinalityWatches   [][]*cardinality     // The cardinality constraints counting each literal.
+	linearWatches        [][]linearWatch      // The linear constraints summing each literal.
+
+	// XOR constraints are propagated together by elimination, over a column for each variable they mention.
+	xorVars       []Var
+	xorColumns    []int // The column of each variable, or -1.
+	xorPropagated int   // The trail length when the XOR constraints were last eliminated.
+
+	assigns     Assignment
+	levels      []int
+	reasons     []*clause // The clause that implied each assignment. Nil for decisions.
+	trail       []Lit     // Assigned literals, in assignment order.
+	trailLimits []int     // The trail index at which each decision level starts.
+	propagated  int       // Trail index of the next literal to propagate.
+
+	// Composites are evaluated against named state, so assignments to their variables are mirrored here.
+	compositeState map[string]bool
+	inComposite    []bool
+
+	seen []bool // Scratch space for conflict analysis.
+
+	// Learned clauses are shared between portfolio workers, if set.
+	exports func(lits []Lit)
+	imports <-chan []Lit
+
+	clauseIncrement float64 // The amount to bump learned clause activities by.
+	levelStamps     []int   // Scratch space for computing LBDs, marking each level with the stamp it was last seen.
+	levelStamp      int
+	reducedAt       int // The conflict count at the last reduction of the learned clauses.
+	reduceAbove     int // The learned clause count past which the next reduction is due, if over the size limit.
+
+	assumptions []Lit // Literals decided before any others, for incremental solving.
+	failed      []Lit // The assumptions responsible for the last search being unsatisfiable.
+
+	heuristic BranchingHeuristic
+	phases    []bool // The value each variable was last assigned, for phase saving.
+
+	options Options
+	stats   Stats
+	ok      bool // False if the formula is known to be unsatisfiable.
+}
+
+// newSolver creates a solver for the given formula.
+func newSolver(formula ConjunctiveFormula) *solver {
+	return newCompiledSolver(formula.Compile(NewVariableTable()))
+}
+
+// newCompiledSolver creates a solver for a compiled formula, sharing its variable table.
+func newCompiledSolver(compiled CompiledFormula) *solver {
+	s := &solver{
+		variables:       compiled.Variables(),
+		compositeState:  make(map[string]bool),
+		clauseIncrement: 1,
+		ok:              true,
+	}
+
+	s.grow()
+	for _, lits := range compiled.Clauses() {
+		s.addClause(lits)
+	}
+	for _, c := range compiled.Composites() {
+		s.addComposite(c)
+	}
+	for _, c := range compiled.Cardinalities() {
+		s.addCardinality(c)
+	}
+	for _, c := range compiled.Linears() {
+		s.addLinear(c)
+	}
+	for _, c := range compiled.Xors() {
+		s.addXor(c)
+	}
+
+	return s
+}
+
+// grow extends the per-variable state to cover every interned variable.
+func (s *solver) grow() {
+	for len(s.assigns) < s.variables.Len() {
+		s.assigns = append(s.assigns, LUndef)
+		s.levels = append(s.levels, 0)
+		s.reasons = append(s.reasons, nil)
+		s.watches = append(s.watches, nil, nil)
+		s.compositeOccurrences = append(s.compositeOccurrences, nil)
+		s.cardinalityWatches = append(s.cardinalityWatches, nil, nil)
+		s.linearWatches = append(s.linearWatches, nil, nil)
+		s.xorColumns = append(s.xorColumns, -1)
+		s.inComposite = append(s.inComposite, false)
+		s.seen = append(s.seen, false)
+		s.phases = append(s.phases, true)
+	}
+}
+
+// addClause adds an original clause to the solver. The solver must be at the root level.
+// Returns the stored clause, or nil if the clause was satisfied, empty or unit, so didn't need storing.
+func (s *solver) addClause(lits []Lit) *clause {
+	return s.insertClause(lits, false)
+}
+
+// insertClause simplifies a clause against the root level assignment, and stores it as an original or learned clause.
+func (s *solver) insertClause(lits []Lit, learnt bool) *clause {
+	lits = append([]Lit(nil), lits...)
+	sort.Slice(lits, func(i, j int) bool { return lits[i] < lits[j] })
+
+	// A literal and its negation are adjacent once sorted.
+	kept := lits[:0]
+	for i, l := range lits {
+		if s.assigns.Value(l) == LTrue || (i > 0 && l == lits[i-1].Not()) {
+			// OR(true, ...) = OR(x, ~x, ...) = true
+			return nil
+		}
+		if s.assigns.Value(l) == LFalse || (i > 0 && l == lits[i-1]) {
+			// OR(false, ...) = OR(...), and OR(x, x, ...) = OR(x, ...)
+			continue
+		}
+		kept = append(kept, l)
+	}
+
+	switch len(kept) {
+	case 0:
+		s.ok = false
+		return nil
+	case 1:
+		s.enqueue(kept[0], nil)
+		return nil
+	default:
+		c := &clause{lits: kept}
+		if learnt {
+			c.learnt, c.lbd, c.activity = true, len(kept), s.clauseIncrement
+			s.learnts = append(s.learnts, c)
+		} else {
+			s.clauses = append(s.clauses, c)
+		}
+		s.watch(c)
+		return c
+	}
+}
+
+// addComposite adds an original clause containing composite literals to the solver.
+func (s *solver) addComposite(c DisjunctiveClause) {
+	cc := &compositeClause{}
+	mentioned := make(map[Var]bool)
+	order := make([]Var, 0) // The mentioned variables in order, so that propagation order is reproducible.
+	for _, literal := range c.literals {
+		if !isComposite(literal) {
+			l := s.variables.Lit(literal)
+			cc.lits = append(cc.lits, l)
+			if !mentioned[l.Var()] {
+				order = append(order, l.Var())
+			}
+			mentioned[l.Var()] = true
+			continue
+		}
+
+		cc.composites = append(cc.composites, literal)
+		for _, name := range literal.Names() {
+			v := s.variables.Intern(name)
+			if !mentioned[v] {
+				cc.vars = append(cc.vars, v)
+				order = append(order, v)
+			}
+			mentioned[v] = true
+			if !s.inComposite[v] && s.assigns[v] != LUndef {
+				// Assigned before any composite mentioned it, so the assignment was never mirrored.
+				s.compositeState[name] = s.assigns[v] == LTrue
+			}
+			s.inComposite[v] = true
+		}
+	}
+
+	s.composites = append(s.composites, cc)
+	for _, v := range order {
+		s.compositeOccurrences[v] = append(s.compositeOccurrences[v], cc)
+	}
+}
+
+// watch starts watching the first two literals of a clause.
+func (s *solver) watch(c *clause) {
+	s.watches[c.lits[0].Not()] = append(s.watches[c.lits[0].Not()], c)
+	s.watches[c.lits[1].Not()] = append(s.watches[c.lits[1].Not()], c)
+}
+
+// solve searches for a satisfying assignment, leaving it assigned if one is found.
+func (s *solver) solve() Status {
+	start := time.Now()
+	defer func() {
+		s.stats.Time += time.Since(start)
+	}()
+	s.updatePeakClauses()
+	s.failed = nil
+
+	if proof := s.options.Proof; proof != nil {
+		defer proof.flush()
+		if len(s.composites) > 0 {
+			composite := s.composites[0].composites[0]
+			proof.fail(fmt.Errorf("cannot prove composite clause (%s): expand it into plain clauses first", composite))
+		}
+		if len(s.cardinalities) > 0 {
+			proof.fail(fmt.Errorf("cannot prove cardinality constraints: expand them into clauses with ToCNF first"))
+		}
+		if len(s.linears) > 0 {
+			proof.fail(fmt.Errorf("cannot prove linear constraints: expand them into clauses with ToCNF first"))
+		}
+		if len(s.xors) > 0 {
+			proof.fail(fmt.Errorf("cannot prove XOR constraints: expand them into clauses with ToCNF first"))
+		}
+	}
+
+	if !s.ok || !s.propagateAll() {
+		s.refute()
+		return Unsatisfiable
+	}
+
+	ctx := s.options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !s.options.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, s.options.Deadline)
+		defer cancel()
+	}
+
+	if s.heuristic == nil {
+		s.heuristic = s.options.Heuristic
+	}
+	if s.heuristic == nil {
+		s.heuristic = NewVSIDS(0)
+	}
+	clauses := make([][]Lit, 0, len(s.clauses))
+	for _, c := range s.clauses {
+		clauses = append(clauses, c.lits)
+	}
+	for _, c := range s.cardinalities {
+		// At most all but one of the literals being true is a clause over their negations, like at least one cell value.
+		if c.max == len(c.lits)-1 {
+			negated := make([]Lit, 0, len(c.lits))
+			for _, l := range c.lits {
+				negated = append(negated, l.Not())
+			}
+			clauses = append(clauses, negated)
+		}
+	}
+	s.heuristic.Reset(s.variables, clauses)
+
+	restarts := s.options.Restarts
+	if restarts == nil {
+		restarts = NewLubyRestarts(0)
+	}
+	restarts.Reset()
+	restartInterval, restartConflicts := restarts.Next(), s.stats.Conflicts
+
+	// Budgets apply to each call.
+	decisions, conflicts := s.stats.Decisions, s.stats.Conflicts
+	for {
+		select {
+		case <-ctx.Done():
+			s.cancelUntil(0)
+			return Unknown
+		default:
+		}
+
+		if conflict := s.propagate(); conflict != nil {
+			s.stats.Conflicts++
+			if observer := s.options.Observer; observer != nil {
+				observer.OnConflict(s.variables.literals(conflict.lits), SearchState{s})
+			}
+			level := s.maxLevel(conflict.lits)
+			if level == 0 {
+				s.refute()
+				return Unsatisfiable
+			}
+			// Explanations from composite literals may not involve the current level.
+			s.cancelUntil(level)
+
+			learnt, backjumpLevel := s.analyze(conflict)
+			s.cancelUntil(backjumpLevel)
+			s.learn(learnt)
+			s.decayClauses()
+			continue
+		}
+
+		if s.shouldReduce() {
+			s.reduceLearnts()
+		}
+
+		if max := s.options.MaxConflicts; max > 0 && s.stats.Conflicts-conflicts >= max {
+			s.cancelUntil(0)
+			return Unknown
+		}
+
+		if restartInterval > 0 && s.stats.Conflicts-restartConflicts >= restartInterval {
+			restartInterval, restartConflicts = restarts.Next(), s.stats.Conflicts
+			if s.decisionLevel() > 0 {
+				s.stats.Restarts++
+				s.cancelUntil(0)
+				continue
+			}
+		}
+
+		if s.decisionLevel() == 0 && s.importClauses() {
+			if !s.ok {
+				s.refute()
+				return Unsatisfiable
+			}
+			continue
+		}
+
+		// Assumptions are decided first, one per level.
+		decision, assumed := Lit(0), false
+		for !assumed && s.decisionLevel() < len(s.assumptions) {
+			p := s.assumptions[s.decisionLevel()]
+			switch s.assigns.Value(p) {
+			case LTrue:
+				// Already implied, so open an empty level to keep levels in step with assumptions.
+				s.trailLimits = append(s.trailLimits, len(s.trail))
+			case LFalse:
+				s.failed = s.analyzeFinal(p)
+				return Unsatisfiable
+			default:
+				decision, assumed = p, true
+			}
+		}
+		if !assumed {
+			v, ok := s.heuristic.Select(s.assigns)
+			if !ok {
+				if observer := s.options.Observer; observer != nil {
+					observer.OnSolution(s.model())
+				}
+				return Satisfiable
+			}
+			decision = NewLit(v, !s.polarity(v))
+		}
+
+		if max := s.options.MaxDecisions; max > 0 && s.stats.Decisions-decisions >= max {
+			s.cancelUntil(0)
+			return Unknown
+		}
+		s.stats.Decisions++
+
+		if observer := s.options.Observer; observer != nil {
+			observer.OnDecision(s.variables.Literal(decision), SearchState{s})
+		}
+		s.trailLimits = append(s.trailLimits, len(s.trail))
+		if s.decisionLevel() > s.stats.MaxDepth {
+			s.stats.MaxDepth = s.decisionLevel()
+		}
+		s.enqueue(decision, nil)
+	}
+}
+
+// polarity returns the value to try first when branching on a variable.
+func (s *solver) polarity(v Var) bool {
+	switch s.options.Phase {
+	case PhaseFalse:
+		return false
+	case PhaseSaving:
+		return s.phases[v]
+	default:
+		return true
+	}
+}
+
+// model returns the current assignment, keyed by name.
+func (s *solver) model() map[string]bool {
+	return s.assigns.State(s.variables)
+}
+
+// block adds a clause excluding the current assignment to the given variables, and returns to the root level.
+// This lets a search continue past a solution to find another.
+func (s *solver) block(vars []Var) {
+	lits := make([]Lit, 0, len(vars))
+	for _, v := range vars {
+		if s.assigns[v] != LUndef {
+			lits = append(lits, NewLit(v, s.assigns[v] == LTrue))
+		}
+	}
+
+	s.cancelUntil(0)
+	s.addClause(lits)
+}
+
+// assume assigns the given state at the root level.
+// Returns false if the state conflicts with the formula.
+func (s *solver) assume(state map[string]bool) bool {
+	for name, value := range state {
+		l := NewLit(s.variables.Intern(name), !value)
+		s.grow()
+
+		switch s.assigns.Value(l) {
+		case LUndef:
+			s.enqueue(l, nil)
+		case LFalse:
+			s.ok = false
+		}
+	}
+	return s.ok
+}
+
+// propagateAll inspects every composite clause, cardinality constraint, linear constraint and XOR constraint once,
+// then propagates the results.
+// This picks up constraints that are decided before any assignment. Unit clauses were already assigned when added.
+// Returns false on conflict.
+func (s *solver) propagateAll() bool {
+	for _, cc := range s.composites {
+		status, implied, _ := s.inspectComposite(cc)
+		switch status {
+		case conflicting:
+			return false
+		case unit:
+			s.enqueue(implied, nil)
+		}
+	}
+	for _, c := range s.cardinalities {
+		if s.propagateCardinality(c) != nil {
+			return false
+		}
+	}
+	for _, c := range s.linears {
+		if s.propagateLinear(c) != nil {
+			return false
+		}
+	}
+	if s.eliminate() != nil {
+		return false
+	}
+	s.xorPropagated = len(s.trail)
+	return s.propagate() == nil
+}
+
+// propagate performs unit propagation on every unpropagated assignment in the trail.
+// Once nothing is left to propagate, the XOR constraints are eliminated if anything was assigned since they last were,
+// and propagation continues from whatever they imply.
+// Returns a conflicting clause if one is found.
+func (s *solver) propagate() *clause {
+	if s.options.Profile {
+		start, compositeTime := time.Now(), s.stats.CompositeTime
+		defer func() {
+			s.stats.PropagationTime += time.Since(start) - (s.stats.CompositeTime - compositeTime)
+		}()
+	}
+
+	for s.propagated < len(s.trail) {
+		p := s.trail[s.propagated]
+		s.propagated++
+
+		if conflict := s.propagateWatches(p); conflict != nil {
+			return conflict
+		}
+
+		for _, cc := range s.compositeOccurrences[p.Var()] {
+			status, implied, reason := s.inspectComposite(cc)
+			switch status {
+			case conflicting:
+				return reason
+			case unit:
+				s.enqueue(implied, reason)
+			}
+		}
+
+		for _, c := range s.cardinalityWatches[p] {
+			if conflict := s.propagateCardinality(c); conflict != nil {
+				return conflict
+			}
+		}
+
+		for _, w := range s.linearWatches[p] {
+			if conflict := s.propagateLinear(w.c); conflict != nil {
+				return conflict
+			}
+		}
+
+		if s.propagated == len(s.trail) && s.xorPropagated < len(s.trail) {
+			s.xorPropagated = len(s.trail)
+			if conflict := s.eliminate(); conflict != nil {
+				return conflict
+			}
+		}
+	}
+	return nil
+}
+
+// propagateWatches inspects the clauses watching the negation of a newly true literal.
+// Each clause either finds a new literal to watch, or is unit or conflicting.
+// Returns a conflicting clause if one is found.
+func (s *solver) propagateWatches(p Lit) *clause {
+	falseLit := p.Not()
+	watchers := s.watches[p]
+	kept := watchers[:0]
+
+	for i, c := range watchers {
+		// Keep the false literal second.
+		if c.lits[0] == falseLit {
+			c.lits[0], c.lits[1] = c.lits[1], c.lits[0]
+		}
+
+		// The clause is already satisfied by its other watch.
+		if s.assigns.Value(c.lits[0]) == LTrue {
+			kept = append(kept, c)
+			continue
+		}
+
+		// Look for a new literal to watch.
+		moved := false
+		for k := 2; k < len(c.lits); k++ {
+			if s.assigns.Value(c.lits[k]) != LFalse {
+				c.lits[1], c.lits[k] = c.lits[k], c.lits[1]
+				s.watches[c.lits[1].Not()] = append(s.watches[c.lits[1].Not()], c)
+				moved = true
+				break
+			}
+		}
+		if moved {
+			continue
+		}
+
+		// The clause is unit or conflicting.
+		kept = append(kept, c)
+		if s.assigns.Value(c.lits[0]) == LFalse {
+			kept = append(kept, watchers[i+1:]...)
+			s.watches[p] = kept
+			return c
+		}
+		s.enqueue(c.lits[0], c)
+	}
+
+	s.watches[p] = kept
+	return nil
+}
+
+// inspectComposite evaluates a composite clause under the current assignment.
+// If the clause is unit, returns the implied literal. If the clause is unit or conflicting, also returns a reason:
+// a plain clause whose literals are all false, apart from the implied literal.
+func (s *solver) inspectComposite(cc *compositeClause) (clauseStatus, Lit, *clause) {
+	if s.options.Profile {
+		start := time.Now()
+		defer func() {
+			s.stats.CompositeTime += time.Since(start)
+		}()
+	}
+
+	var implied Lit
+	undetermined := 0
+	for _, l := range cc.lits {
+		switch s.assigns.Value(l) {
+		case LTrue:
+			return satisfied, 0, nil
+		case LUndef:
+			undetermined++
+			implied = l
+		}
+	}
+
+	for _, literal := range cc.composites {
+		switch value := literal.Evaluate(s.compositeState).(type) {
+		case bool:
+			if value {
+				return satisfied, 0, nil
+			}
+		case Literal:
+			if isComposite(value) {
+				// Composites can't be propagated until they reduce to a plain literal.
+				undetermined += 2
+				continue
+			}
+			undetermined++
+			implied = s.variables.Lit(value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+
+	switch undetermined {
+	case 0:
+		return conflicting, 0, s.explain(cc, nil)
+	case 1:
+		return unit, implied, s.explain(cc, &implied)
+	default:
+		return unresolved, 0, nil
+	}
+}
+
+// explain returns a reason clause for a composite clause being unit on implied, or conflicting if implied is nil.
+// Plain literals in the clause are false, so they explain themselves. Composite literals are explained by the
+// assignments to their variables.
+func (s *solver) explain(cc *compositeClause, implied *Lit) *clause {
+	lits := make([]Lit, 0, len(cc.lits)+len(cc.vars)+1)
+	if implied != nil {
+		lits = append(lits, *implied)
+	}
+
+	for _, l := range cc.lits {
+		if implied == nil || l.Var() != implied.Var() {
+			lits = append(lits, l)
+		}
+	}
+	for _, v := range cc.vars {
+		if s.assigns[v] != LUndef {
+			lits = append(lits, NewLit(v, s.assigns[v] == LTrue))
+		}
+	}
+
+	return &clause{lits: lits}
+}
+
+// analyze derives a learned clause from a conflict, using the first unique implication point.
+// The conflict must involve the current decision level.
+// Returns the learned clause, with the asserting literal first, and the level to backjump to.
+func (s *solver) analyze(conflict *clause) ([]Lit, int) {
+	level := s.decisionLevel()
+	learnt := []Lit{0} // Placeholder for the asserting literal.
+	seen := make([]Var, 0)
+
+	var p Lit
+	skip := Var(-1) // The implied variable of the current reason.
+	pathCount := 0
+	index := len(s.trail) - 1
+	reason := conflict
+	if reason.learnt {
+		s.bumpClause(reason)
+	}
+
+	for {
+		for _, q := range reason.lits {
+			v := q.Var()
+			if v == skip || s.seen[v] || s.levels[v] == 0 {
+				continue
+			}
+			s.seen[v] = true
+			seen = append(seen, v)
+
+			if s.levels[v] == level {
+				pathCount++
+			} else {
+				learnt = append(learnt, q)
+			}
+		}
+
+		// Select the next literal to expand.
+		for !s.seen[s.trail[index].Var()] {
+			index--
+		}
+		p = s.trail[index]
+		index--
+		pathCount--
+
+		if pathCount == 0 {
+			break
+		}
+		reason = s.reasons[p.Var()]
+		skip = p.Var()
+		if reason.learnt {
+			s.bumpClause(reason)
+		}
+	}
+	learnt[0] = p.Not()
+
+	for _, v := range seen {
+		s.seen[v] = false
+	}
+
+	// Backjump to the second highest level in the clause, and keep that literal second.
+	backjumpLevel := 0
+	for i := 1; i < len(learnt); i++ {
+		if l := s.levels[learnt[i].Var()]; l > backjumpLevel {
+			backjumpLevel = l
+			learnt[1], learnt[i] = learnt[i], learnt[1]
+		}
+	}
+
+	return learnt, backjumpLevel
+}
+
+// importClauses adds the learned clauses shared by other workers. The solver must be at the root level.
+// Returns whether any were added.
+func (s *solver) importClauses() bool {
+	imported := false
+	for {
+		select {
+		case lits := <-s.imports:
+			s.insertClause(lits, true)
+			imported = true
+		default:
+			return imported
+		}
+	}
+}
+
+// refute records that the formula is unsatisfiable.
+func (s *solver) refute() {
+	s.ok = false
+	if proof := s.options.Proof; proof != nil {
+		proof.add(nil)
+	}
+}
+
+// analyzeFinal returns the assumptions that imply the negation of the given assumption, including the assumption.
+// Every decision on the trail must be an assumption.
+func (s *solver) analyzeFinal(p Lit) []Lit {
+	failed := []Lit{p}
+	if s.decisionLevel() == 0 || s.levels[p.Var()] == 0 {
+		return failed
+	}
+
+	s.seen[p.Var()] = true
+	for i := len(s.trail) - 1; i >= s.trailLimits[0]; i-- {
+		v := s.trail[i].Var()
+		if !s.seen[v] {
+			continue
+		}
+		s.seen[v] = false
+
+		reason := s.reasons[v]
+		if reason == nil {
+			failed = append(failed, s.trail[i])
+			continue
+		}
+		for _, q := range reason.lits {
+			if q.Var() != v && s.levels[q.Var()] > 0 {
+				s.seen[q.Var()] = true
+			}
+		}
+	}
+	return failed
+}
+
+// learn records a learned clause and asserts its first literal.
+// The solver must already have backjumped to the clause's asserting level.
+func (s *solver) learn(lits []Lit) {
+	c := &clause{lits: lits, learnt: true, lbd: s.lbd(lits), activity: s.clauseIncrement}
+	s.learnts = append(s.learnts, c)
+	s.stats.LearnedClauses++
+	s.updatePeakClauses()
+	if proof := s.options.Proof; proof != nil {
+		proof.add(lits)
+	}
+	if s.exports != nil {
+		s.exports(append([]Lit(nil), lits...))
+	}
+	s.heuristic.Learned(lits)
+	if len(lits) > 1 {
+		s.watch(c)
+	}
+	s.enqueue(lits[0], c)
+}
+
+// enqueue assigns a literal to be true at the current decision level.
+func (s *solver) enqueue(l Lit, reason *clause) {
+	v := l.Var()
+	s.assigns[v] = LiftBool(!l.Negated())
+	s.levels[v] = s.decisionLevel()
+	s.reasons[v] = reason
+	s.trail = append(s.trail, l)
+	for _, c := range s.cardinalityWatches[l] {
+		c.count++
+	}
+	for _, w := range s.linearWatches[l] {
+		w.c.sum += w.weight
+	}
+
+	if s.inComposite[v] {
+		s.compositeState[s.variables.Name(v)] = !l.Negated()
+	}
+
+	if reason != nil {
+		s.stats.Propagations++
+		if observer := s.options.Observer; observer != nil {
+			observer.OnPropagate(s.variables.Literal(l), SearchState{s})
+		}
+	}
+}
+
+// cancelUntil undoes all assignments above the given decision level.
+func (s *solver) cancelUntil(level int) {
+	if s.decisionLevel() <= level {
+		return
+	}
+
+	limit := s.trailLimits[level]
+	for _, l := range s.trail[limit:] {
+		v := l.Var()
+		s.phases[v] = !l.Negated()
+		s.assigns[v] = LUndef
+		s.reasons[v] = nil
+		for _, c := range s.cardinalityWatches[l] {
+			c.count--
+		}
+		for _, w := range s.linearWatches[l] {
+			w.c.sum -= w.weight
+		}
+		if s.heuristic != nil {
+			s.heuristic.Unassigned(v)
+		}
+		if s.inComposite[v] {
+			delete(s.compositeState, s.variables.Name(v))
+		}
+	}
+	s.trail = s.trail[:limit]
+	s.trailLimits = s.trailLimits[:level]
+	s.propagated = limit
+	if s.xorPropagated > limit {
+		s.xorPropagated = limit
+	}
+
+	if observer := s.options.Observer; observer != nil {
+		observer.OnBacktrack(level, SearchState{s})
+	}
+}
+
+// maxLevel returns the highest decision level among the given literals.
+func (s *solver) maxLevel(lits []Lit) (level int) {
+	for _, l := range lits {
+		if l := s.levels[l.Var()]; l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// updatePeakClauses records the current number of clauses, if it is a new peak.
+func (s *solver) updatePeakClauses() {
+	if count := len(s.clauses) + len(s.learnts) + len(s.composites); count > s.stats.PeakClauses {
+		s.stats.PeakClauses = count
+	}
+}
+
+func (s *solver) decisionLevel() int {
+	return len(s.trailLimits)
+}
+
+// isComposite returns whether a literal is a composite literal or the negation of one.
+func isComposite(literal Literal) bool {
+	switch l := literal.(type) {
+	case CompositeLiteral:
+		return true
+	case NegativeLiteral:
+		return isComposite(l.literal)
+	default:
+		return false
+	}
+}
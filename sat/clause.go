@@ -77,6 +77,16 @@ func (c DisjunctiveClause) Evaluate(state map[string]bool) interface{} {
 	return DisjunctiveClause{literals}
 }
 
+// hasComposite returns whether this clause contains a composite literal.
+func (c DisjunctiveClause) hasComposite() bool {
+	for _, literal := range c.literals {
+		if isComposite(literal) {
+			return true
+		}
+	}
+	return false
+}
+
 // ToFormula returns a formula containing this clause.
 func (c DisjunctiveClause) ToFormula() ConjunctiveFormula {
 	return NewConjunctiveFormula([]DisjunctiveClause{c})
@@ -151,7 +161,7 @@ func (c ConjunctiveClause) ToCNF() ConjunctiveFormula {
 	for _, literal := range c.literals {
 		clauses = append(clauses, NewDisjunctiveClause(literal))
 	}
-	return ConjunctiveFormula{clauses}
+	return ConjunctiveFormula{clauses: clauses}
 }
 
 func (c ConjunctiveClause) String() string {
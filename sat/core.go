@@ -0,0 +1,76 @@
+package sat
+
+import (
+	"fmt"
+	"sort"
+)
+
+// coreSelectorPrefix starts the names of the variables that switch groups on and off in UnsatisfiableCore.
+const coreSelectorPrefix = "core#"
+
+// UnsatisfiableCore finds groups of clauses that can't all hold together.
+// Returns the indices of the groups in the core, in increasing order. The core is minimal: dropping any one of its
+// groups makes the rest satisfiable. If every group can hold together, returns Satisfiable and no core.
+// If the search is stopped by its options, returns Unknown and no core.
+//
+// Each group is guarded by a selector variable, and the groups are solved assuming every selector. The failed
+// assumptions give a core, which is then minimized by trying to drop each group in turn.
+func UnsatisfiableCore(groups []ConjunctiveFormula, options Options) ([]int, Status) {
+	s := NewSolver(options)
+	selectors := make([]Literal, len(groups))
+	indices := make(map[string]int, len(groups))
+	for i, group := range groups {
+		selector := NewLiteral(fmt.Sprintf("%s%d", coreSelectorPrefix, i))
+		selectors[i] = selector
+		indices[selector.Name()] = i
+
+		// Cardinality, linear and XOR constraints can't be guarded, so they are expanded into clauses that can.
+		guard := NewDisjunctiveClause(selector.Negate())
+		for _, c := range group.ToCNF(SequentialCounterEncoding).clauses {
+			s.AddClause(c.Or(guard))
+		}
+	}
+
+	result := s.Solve(selectors...)
+	if result.Status != Unsatisfiable {
+		return nil, result.Status
+	}
+	core := coreIndices(result.FailedAssumptions, indices)
+
+	// Try dropping each group in turn. If the rest is still unsatisfiable, the core shrinks to its failed assumptions.
+	// Otherwise the group is needed, and stays needed in every smaller core.
+	needed := make(map[int]bool)
+	for i := 0; i < len(core); i++ {
+		if needed[core[i]] {
+			continue
+		}
+		assumptions := make([]Literal, 0, len(core)-1)
+		for _, index := range core {
+			if index != core[i] {
+				assumptions = append(assumptions, selectors[index])
+			}
+		}
+
+		switch result := s.Solve(assumptions...); result.Status {
+		case Satisfiable:
+			needed[core[i]] = true
+		case Unsatisfiable:
+			core = coreIndices(result.FailedAssumptions, indices)
+			i = -1
+		default:
+			return nil, Unknown
+		}
+	}
+
+	return core, Unsatisfiable
+}
+
+// coreIndices returns the sorted group indices of the given selectors.
+func coreIndices(selectors []Literal, indices map[string]int) []int {
+	core := make([]int, 0, len(selectors))
+	for _, selector := range selectors {
+		core = append(core, indices[selector.Name()])
+	}
+	sort.Ints(core)
+	return core
+}
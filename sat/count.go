@@ -0,0 +1,313 @@
+package sat
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CountModels counts the satisfying assignments of a formula exactly.
+// If variables are named, only assignments to them are counted: two models that differ only on other variables, like
+// auxiliary encoding variables, count once. As in CountSolutions, names missing from the formula are ignored, and
+// with no names every variable in the formula is counted.
+//
+// The count is found by branching on variables, splitting the remaining clauses into independent components whose
+// counts multiply, and caching the count of each component, since the same components recur across branches.
+// Cardinality, linear and XOR constraints are expanded into clauses first, and the count is projected away from any
+// auxiliary variables. Composite literals can't be split into components, so formulas containing them are rejected.
+func CountModels(formula ConjunctiveFormula, projection ...string) (*big.Int, error) {
+	// Constraints can mention variables their expansions drop, like a literal cancelled out of an XOR, which are free.
+	// Compiling the formula first keeps them in the table.
+	variables := formula.Compile(NewVariableTable()).Variables()
+	if (len(formula.cardinalities) > 0 || len(formula.linears) > 0 || len(formula.xors) > 0) && len(projection) == 0 {
+		for v := 0; v < variables.Len(); v++ {
+			projection = append(projection, variables.Name(Var(v)))
+		}
+	}
+	compiled := formula.expand(countingEncoding).Compile(variables)
+	if composites := compiled.Composites(); len(composites) > 0 {
+		return nil, fmt.Errorf("cannot count models of composite clause (%s): expand it into plain clauses first", composites[0])
+	}
+
+	c := &modelCounter{variables: variables, projected: make([]bool, variables.Len()), cache: make(map[string]*big.Int)}
+	for v := range c.projected {
+		c.projected[v] = len(projection) == 0
+	}
+	for _, name := range projection {
+		if v, ok := variables.Lookup(name); ok {
+			c.projected[v] = true
+		}
+	}
+
+	clauses := make([][]Lit, 0, len(compiled.Clauses()))
+	for _, lits := range compiled.Clauses() {
+		if lits, ok := normalize(lits); ok {
+			clauses = append(clauses, lits)
+		}
+	}
+
+	// Projected variables in no clause are free, as are those that vanish when the initial units are propagated.
+	clauses, assigned, ok := propagateUnits(clauses, nil)
+	if !ok {
+		return big.NewInt(0), nil
+	}
+	free := c.projectedCount(allVars(variables.Len())) - c.projectedCount(occurring(clauses)) - c.projectedCount(litVars(assigned))
+	return c.scale(c.count(clauses), free), nil
+}
+
+// maxBinomialClauses is the most clauses a cardinality constraint can expand to without auxiliary variables when
+// counting. Auxiliary variables don't change the count, but the counter has to search them to project them away.
+const maxBinomialClauses = 256
+
+// countingEncoding chooses how to expand a cardinality constraint for counting.
+func countingEncoding(c CardinalityConstraint) CardinalityEncoding {
+	n := len(c.literals)
+	upper, lower := binomialClauses(n, c.max, maxBinomialClauses), binomialClauses(n, n-c.min, maxBinomialClauses)
+	if upper >= 0 && lower >= 0 && upper+lower <= maxBinomialClauses {
+		return binomialEncoding
+	}
+	return SequentialCounterEncoding
+}
+
+// modelCounter counts the models of sets of clauses, projected onto some variables.
+type modelCounter struct {
+	variables *VariableTable
+	projected []bool
+	cache     map[string]*big.Int // The count of each component seen, by its clauses.
+}
+
+// count counts the assignments to the projected variables occurring in the clauses that extend to a model.
+// The clauses must be normalized, and free of units.
+func (c *modelCounter) count(clauses [][]Lit) *big.Int {
+	total := big.NewInt(1)
+	for _, component := range components(clauses) {
+		total.Mul(total, c.countComponent(component))
+		if total.Sign() == 0 {
+			break
+		}
+	}
+	return total
+}
+
+// countComponent counts the models of clauses that share no variables with the rest.
+func (c *modelCounter) countComponent(clauses [][]Lit) *big.Int {
+	key := componentKey(clauses)
+	if count, ok := c.cache[key]; ok {
+		return count
+	}
+
+	count := big.NewInt(0)
+	branch, ok := c.branchVariable(clauses)
+	if !ok {
+		// Nothing left to count, so there is one projected assignment if the clauses can be satisfied.
+		if c.satisfiable(clauses) {
+			count.SetInt64(1)
+		}
+		c.cache[key] = count
+		return count
+	}
+
+	before := c.projectedCount(occurring(clauses))
+	for _, negated := range []bool{false, true} {
+		simplified, assigned, ok := propagateUnits(clauses, []Lit{NewLit(branch, negated)})
+		if !ok {
+			continue
+		}
+		// Projected variables that vanished without being assigned can take either value.
+		free := before - c.projectedCount(occurring(simplified)) - c.projectedCount(litVars(assigned))
+		count.Add(count, c.scale(c.count(simplified), free))
+	}
+
+	c.cache[key] = count
+	return count
+}
+
+// branchVariable returns a projected variable from the shortest clause with one, preferring those occurring most
+// often, or false if there is none. Short clauses fail fast, like picking the cell with the fewest candidates.
+func (c *modelCounter) branchVariable(clauses [][]Lit) (Var, bool) {
+	occurrences := make(map[Var]int)
+	shortest := -1
+	for i, lits := range clauses {
+		for _, l := range lits {
+			occurrences[l.Var()]++
+			if c.projected[l.Var()] && (shortest < 0 || len(lits) < len(clauses[shortest])) {
+				shortest = i
+			}
+		}
+	}
+	if shortest < 0 {
+		return 0, false
+	}
+
+	best := clauses[shortest][0].Var()
+	for _, l := range clauses[shortest] {
+		if v := l.Var(); c.projected[v] && (!c.projected[best] || occurrences[v] > occurrences[best]) {
+			best = v
+		}
+	}
+	return best, true
+}
+
+// satisfiable returns whether the clauses have a model.
+func (c *modelCounter) satisfiable(clauses [][]Lit) bool {
+	s := newSolver(EmptyConjunctiveFormula())
+	s.variables = c.variables
+	s.grow()
+	for _, lits := range clauses {
+		s.addClause(lits)
+	}
+	return s.solve() == Satisfiable
+}
+
+// projectedCount returns how many of the given variables are projected.
+func (c *modelCounter) projectedCount(vars []Var) int {
+	count := 0
+	for _, v := range vars {
+		if c.projected[v] {
+			count++
+		}
+	}
+	return count
+}
+
+// scale multiplies a count by 2^free.
+func (c *modelCounter) scale(count *big.Int, free int) *big.Int {
+	return new(big.Int).Lsh(count, uint(free))
+}
+
+// propagateUnits assigns the given literals and every unit clause that results, and simplifies the clauses.
+// Returns the remaining clauses, none of which are unit, and every literal assigned, or false on conflict.
+func propagateUnits(clauses [][]Lit, units []Lit) ([][]Lit, []Lit, bool) {
+	assigned := make(map[Lit]bool)
+	order := make([]Lit, 0)
+	for {
+		for _, l := range units {
+			if assigned[l.Not()] {
+				return nil, nil, false
+			}
+			if !assigned[l] {
+				assigned[l] = true
+				order = append(order, l)
+			}
+		}
+		units = nil
+
+		simplified := make([][]Lit, 0, len(clauses))
+	clauses:
+		for _, lits := range clauses {
+			kept := lits // Only copied once a literal is removed, since most clauses are untouched.
+			for i, l := range lits {
+				if assigned[l] {
+					continue clauses
+				}
+				if assigned[l.Not()] {
+					if len(kept) == len(lits) {
+						kept = append(make([]Lit, 0, len(lits)), lits[:i]...)
+					}
+				} else if len(kept) < len(lits) {
+					kept = append(kept, l)
+				}
+			}
+
+			switch len(kept) {
+			case 0:
+				return nil, nil, false
+			case 1:
+				units = append(units, kept[0])
+			default:
+				simplified = append(simplified, kept)
+			}
+		}
+		clauses = simplified
+
+		if len(units) == 0 {
+			return clauses, order, true
+		}
+	}
+}
+
+// components splits clauses into groups that share no variables.
+func components(clauses [][]Lit) [][][]Lit {
+	parents := make(map[Var]Var)
+	var find func(v Var) Var
+	find = func(v Var) Var {
+		parent, ok := parents[v]
+		if !ok || parent == v {
+			return v
+		}
+		root := find(parent)
+		parents[v] = root
+		return root
+	}
+	for _, lits := range clauses {
+		root := find(lits[0].Var())
+		for _, l := range lits[1:] {
+			if other := find(l.Var()); other != root {
+				parents[other] = root
+			}
+		}
+	}
+
+	groups := make(map[Var]int)
+	result := make([][][]Lit, 0)
+	for _, lits := range clauses {
+		root := find(lits[0].Var())
+		index, ok := groups[root]
+		if !ok {
+			index = len(result)
+			groups[root] = index
+			result = append(result, nil)
+		}
+		result[index] = append(result[index], lits)
+	}
+	return result
+}
+
+// componentKey identifies a set of normalized clauses regardless of their order.
+func componentKey(clauses [][]Lit) string {
+	keys := make([]string, 0, len(clauses))
+	for _, lits := range clauses {
+		numbers := make([]string, 0, len(lits))
+		for _, l := range lits {
+			numbers = append(numbers, strconv.Itoa(int(l)))
+		}
+		keys = append(keys, strings.Join(numbers, " "))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// occurring returns the variables occurring in the clauses.
+func occurring(clauses [][]Lit) []Var {
+	seen := make(map[Var]bool)
+	vars := make([]Var, 0)
+	for _, lits := range clauses {
+		for _, l := range lits {
+			if !seen[l.Var()] {
+				seen[l.Var()] = true
+				vars = append(vars, l.Var())
+			}
+		}
+	}
+	return vars
+}
+
+// litVars returns the variables of the given literals.
+func litVars(lits []Lit) []Var {
+	vars := make([]Var, 0, len(lits))
+	for _, l := range lits {
+		vars = append(vars, l.Var())
+	}
+	return vars
+}
+
+// allVars returns the first n variables.
+func allVars(n int) []Var {
+	vars := make([]Var, 0, n)
+	for v := 0; v < n; v++ {
+		vars = append(vars, Var(v))
+	}
+	return vars
+}
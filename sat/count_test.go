@@ -0,0 +1,109 @@
+package sat
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// enumerateModels counts the assignments to the named variables satisfying a formula, by brute force. Models that
+// agree on the projected variables count once.
+func enumerateModels(formula ConjunctiveFormula, names []string, projection []string) int64 {
+	seen := make(map[string]bool)
+	for _, state := range assignments(names) {
+		if formula.Evaluate(state) != true {
+			continue
+		}
+		key := make([]string, 0, len(projection))
+		for _, name := range projection {
+			if state[name] {
+				key = append(key, name)
+			}
+		}
+		seen[strings.Join(key, " ")] = true
+	}
+	return int64(len(seen))
+}
+
+// randomConstraint returns a random cardinality, linear or XOR constraint over the named variables.
+func randomConstraint(r *rand.Rand, names []string) ConjunctiveFormula {
+	literals := make([]Literal, 2+r.Intn(len(names)-1))
+	for i := range literals {
+		literals[i] = randomLiteral(r, names)
+	}
+
+	switch r.Intn(3) {
+	case 0:
+		k := r.Intn(len(literals) + 1)
+		switch r.Intn(3) {
+		case 0:
+			return AtMost(k, literals...).ToFormula()
+		case 1:
+			return AtLeast(k, literals...).ToFormula()
+		default:
+			return Exactly(k, literals...).ToFormula()
+		}
+	case 1:
+		terms := make([]WeightedLiteral, len(literals))
+		total := 0
+		for i, literal := range literals {
+			terms[i] = Weighted(1+r.Intn(5), literal)
+			total += terms[i].Weight()
+		}
+		bound := r.Intn(total + 1)
+		switch r.Intn(3) {
+		case 0:
+			return SumAtMost(bound, terms...).ToFormula()
+		case 1:
+			return SumAtLeast(bound, terms...).ToFormula()
+		default:
+			return SumEquals(bound, terms...).ToFormula()
+		}
+	default:
+		if r.Intn(2) == 0 {
+			return OddParity(literals...).ToFormula()
+		}
+		return EvenParity(literals...).ToFormula()
+	}
+}
+
+func TestCountModels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		names := randomNames(3 + r.Intn(7))
+		formula := randomClauses(r, names, r.Intn(3*len(names)))
+		for j := r.Intn(3); j > 0; j-- {
+			formula = formula.And(randomConstraint(r, names))
+		}
+
+		// Mention every variable, so that none is missing from the formula.
+		mentions := make([]Literal, 0, 2*len(names))
+		for _, name := range names {
+			mentions = append(mentions, NewLiteral(name), NewLiteral(name).Negate())
+		}
+		formula = formula.And(NewDisjunctiveClause(mentions...).ToFormula())
+
+		projection := make([]string, 0)
+		for _, name := range names {
+			if r.Intn(2) == 0 {
+				projection = append(projection, name)
+			}
+		}
+
+		for _, projected := range [][]string{nil, projection} {
+			count, err := CountModels(formula, projected...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// With no projection, every variable is counted.
+			counted := projected
+			if len(counted) == 0 {
+				counted = names
+			}
+			if want := big.NewInt(enumerateModels(formula, names, counted)); count.Cmp(want) != 0 {
+				t.Errorf("%s projected onto %v: count = %s, want %s", formula, projected, count, want)
+			}
+		}
+	}
+}
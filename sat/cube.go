@@ -0,0 +1,183 @@
+package sat
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// CubeOptions configure a cube-and-conquer enumeration.
+type CubeOptions struct {
+	Context context.Context // Cancelling the context stops the enumeration early. Nil never cancels.
+	Workers int             // The number of goroutines searching cubes. Zero or less uses one per CPU.
+	// The variables to split the search on. Every consistent assignment to them is a cube, searched separately.
+	// Only variables that solutions are distinct over are used, so that no solution is found in two cubes.
+	Split []string
+}
+
+// SolveAllCubes returns up to limit satisfying assignments of the given formula, like SolveAll, but searches the cubes
+// of its split variables in parallel.
+// Solutions are ordered by cube, with cubes ordered by their split variables taking true before false, so the output
+// is the same on every run. With a limit, the solutions returned are the first in that order.
+// If the context is cancelled, the solutions from cubes that were finished in order are returned.
+func SolveAllCubes(formula ConjunctiveFormula, limit int, names []string, options CubeOptions) []map[string]bool {
+	solutions := make([]map[string]bool, 0)
+	for _, cube := range conquer(formula, limit, names, options, true) {
+		solutions = append(solutions, cube.solutions...)
+	}
+	if limit > 0 && len(solutions) > limit {
+		solutions = solutions[:limit]
+	}
+	return solutions
+}
+
+// CountSolutionsCubes counts the satisfying assignments of the given formula like CountSolutions, but searches the
+// cubes of its split variables in parallel, as in SolveAllCubes.
+func CountSolutionsCubes(formula ConjunctiveFormula, limit int, names []string, options CubeOptions) int {
+	count := 0
+	for _, cube := range conquer(formula, limit, names, options, false) {
+		count += cube.count
+	}
+	if limit > 0 && count > limit {
+		count = limit
+	}
+	return count
+}
+
+// cubeResult is the result of enumerating the solutions in one cube.
+type cubeResult struct {
+	count     int
+	solutions []map[string]bool // Only kept if asked for.
+	done      bool
+}
+
+// conquer enumerates the solutions in each cube on a pool of workers.
+// Returns the results of the cubes that finished, in order, up to the first that didn't.
+func conquer(formula ConjunctiveFormula, limit int, names []string, options CubeOptions, keep bool) []cubeResult {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	base := newSolver(formula)
+	vars := projection(base, names)
+	projected := make(map[Var]bool, len(vars))
+	for _, v := range vars {
+		projected[v] = true
+	}
+	split := make([]Var, 0, len(options.Split))
+	for _, name := range options.Split {
+		if v, ok := base.variables.Lookup(name); ok && projected[v] {
+			split = append(split, v)
+		}
+	}
+
+	cubes := make([][]Lit, 0)
+	if base.ok && base.propagateAll() {
+		base.cubes(split, nil, func(cube []Lit) {
+			cubes = append(cubes, cube)
+		})
+	}
+
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]cubeResult, len(cubes))
+	jobs := make(chan int)
+	var mutex sync.Mutex
+	finished, total := 0, 0 // The number of cubes finished in order, and their solutions.
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := enumerateCube(ctx, formula, cubes[i], vars, limit, keep)
+
+				mutex.Lock()
+				results[i] = result
+				for finished < len(results) && results[finished].done {
+					total += results[finished].count
+					finished++
+				}
+				if limit > 0 && total >= limit {
+					// Every later cube would be cut off by the limit.
+					cancel()
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i := range cubes {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results[:finished]
+}
+
+// cubes calls emit with every assignment to the split variables that propagation doesn't refute, as the literals
+// decided to reach it. Variables already assigned by propagation aren't decided.
+// The solver must be at the root level, with everything propagated.
+func (s *solver) cubes(split []Var, decided []Lit, emit func([]Lit)) {
+	for len(split) > 0 && s.assigns[split[0]] != LUndef {
+		split = split[1:]
+	}
+	if len(split) == 0 {
+		emit(append([]Lit(nil), decided...))
+		return
+	}
+
+	for _, negated := range []bool{false, true} {
+		l := NewLit(split[0], negated)
+		level := s.decisionLevel()
+		s.trailLimits = append(s.trailLimits, len(s.trail))
+		s.enqueue(l, nil)
+		if s.propagate() == nil {
+			s.cubes(split[1:], append(decided, l), emit)
+		}
+		s.cancelUntil(level)
+	}
+}
+
+// enumerateCube enumerates up to limit solutions in a cube, with a fresh solver so that the result doesn't depend on
+// which cubes a worker searched before.
+func enumerateCube(ctx context.Context, formula ConjunctiveFormula, cube []Lit, vars []Var, limit int, keep bool) cubeResult {
+	s := newSolver(formula)
+	s.options.Context = ctx
+	for _, l := range cube {
+		if s.assigns.Value(l) == LUndef {
+			s.enqueue(l, nil)
+		}
+	}
+
+	var result cubeResult
+	for limit <= 0 || result.count < limit {
+		switch s.solve() {
+		case Satisfiable:
+			result.count++
+			if keep {
+				result.solutions = append(result.solutions, s.model())
+			}
+			s.block(vars)
+		case Unsatisfiable:
+			result.done = true
+			return result
+		default:
+			return result
+		}
+	}
+	result.done = true
+	return result
+}
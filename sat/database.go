@@ -0,0 +1,161 @@
+package sat
+
+import "sort"
+
+// ClauseDatabase configures how learned clauses are kept.
+//
+// Learned clauses are scored by their literal block distance (LBD): the number of distinct decision levels among
+// their literals when they were learned. Clauses with a low LBD link few decisions together, and tend to stay useful.
+// Ties are broken by activity, which is bumped whenever a clause takes part in conflict analysis and decays over time.
+//
+// Every so often the database is reduced: the worse half of the clauses is removed, apart from glue clauses and
+// clauses that are currently the reason for an assignment.
+// The zero value uses the defaults.
+type ClauseDatabase struct {
+	ReduceInterval int // Conflicts between reductions. Zero uses 2000, and negative values never reduce.
+	GlueLBD        int // Clauses with at most this LBD are protected from halving. Zero uses 2.
+	// The most learned clauses kept, including glue clauses. Learning past the limit reduces early, and reductions
+	// remove glue clauses if they must. Zero uses 100000, and negative values have no limit.
+	MaxLearnts int
+}
+
+func (db ClauseDatabase) reduceInterval() int {
+	if db.ReduceInterval == 0 {
+		return 2000
+	}
+	return db.ReduceInterval
+}
+
+func (db ClauseDatabase) glueLBD() int {
+	if db.GlueLBD == 0 {
+		return 2
+	}
+	return db.GlueLBD
+}
+
+func (db ClauseDatabase) maxLearnts() int {
+	if db.MaxLearnts == 0 {
+		return 100000
+	}
+	return db.MaxLearnts
+}
+
+// clauseActivityDecay is the factor clause activities decay by on every conflict.
+const clauseActivityDecay = 0.999
+
+// lbd returns the number of distinct decision levels among the given literals.
+func (s *solver) lbd(lits []Lit) int {
+	s.levelStamp++
+	count := 0
+	for _, l := range lits {
+		level := s.levels[l.Var()]
+		for len(s.levelStamps) <= level {
+			s.levelStamps = append(s.levelStamps, 0)
+		}
+		if s.levelStamps[level] != s.levelStamp {
+			s.levelStamps[level] = s.levelStamp
+			count++
+		}
+	}
+	return count
+}
+
+// bumpClause increases the activity of a learned clause that took part in conflict analysis.
+// Its LBD is also updated, since it may have improved under the current assignment.
+func (s *solver) bumpClause(c *clause) {
+	c.activity += s.clauseIncrement
+	if c.activity > 1e20 {
+		// Rescale before activities overflow.
+		for _, learnt := range s.learnts {
+			learnt.activity *= 1e-20
+		}
+		s.clauseIncrement *= 1e-20
+	}
+
+	if c.lbd > s.options.ClauseDatabase.glueLBD() {
+		if lbd := s.lbd(c.lits); lbd < c.lbd {
+			c.lbd = lbd
+		}
+	}
+}
+
+// decayClauses decays the activity of every learned clause, by bumping future clauses more instead.
+func (s *solver) decayClauses() {
+	s.clauseIncrement /= clauseActivityDecay
+}
+
+// shouldReduce returns whether the learned clause database is due for a reduction.
+// Locked clauses can keep the database over the size limit, so after a reduction that leaves it over, the next is due
+// once it grows by another tenth.
+func (s *solver) shouldReduce() bool {
+	db := s.options.ClauseDatabase
+	if max := db.maxLearnts(); max > 0 && len(s.learnts) > max && len(s.learnts) > s.reduceAbove {
+		return true
+	}
+	interval := db.reduceInterval()
+	return interval > 0 && s.stats.Conflicts-s.reducedAt >= interval
+}
+
+// reduceLearnts removes the worse half of the unprotected learned clauses, and any clauses past the size limit.
+func (s *solver) reduceLearnts() {
+	db := s.options.ClauseDatabase
+	s.reducedAt = s.stats.Conflicts
+
+	// Best clauses first, so glue clauses come before the others.
+	sort.SliceStable(s.learnts, func(i, j int) bool {
+		a, b := s.learnts[i], s.learnts[j]
+		if a.lbd != b.lbd {
+			return a.lbd < b.lbd
+		}
+		return a.activity > b.activity
+	})
+
+	unprotected := 0
+	for _, c := range s.learnts {
+		if c.lbd > db.glueLBD() {
+			unprotected++
+		}
+	}
+	limit := len(s.learnts) - unprotected/2
+	if max := db.maxLearnts(); max > 0 && limit > max {
+		limit = max
+	}
+
+	kept := s.learnts[:0]
+	removed := make(map[*clause]bool)
+	for i, c := range s.learnts {
+		if i < limit || s.locked(c) {
+			kept = append(kept, c)
+			continue
+		}
+		removed[c] = true
+		if proof := s.options.Proof; proof != nil {
+			proof.delete(c.lits)
+		}
+	}
+	s.reduceAbove = 0
+	if max := db.maxLearnts(); max > 0 && len(kept) > max {
+		s.reduceAbove = len(kept) + len(kept)/10
+	}
+	if len(removed) == 0 {
+		return
+	}
+	s.learnts = kept
+	s.stats.DeletedClauses += len(removed)
+
+	for l, watchers := range s.watches {
+		keptWatchers := watchers[:0]
+		for _, c := range watchers {
+			if !removed[c] {
+				keptWatchers = append(keptWatchers, c)
+			}
+		}
+		s.watches[l] = keptWatchers
+	}
+}
+
+// locked returns whether a clause is the reason for a current assignment, so must be kept.
+func (s *solver) locked(c *clause) bool {
+	v := c.lits[0].Var()
+	return s.assigns[v] != LUndef && s.reasons[v] == c
+}
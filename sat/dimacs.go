@@ -0,0 +1,157 @@
+package sat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dimacsNamePrefix starts the comment lines that map DIMACS variable numbers back to names.
+const dimacsNamePrefix = "c var "
+
+// WriteDIMACS writes this formula in DIMACS CNF format.
+// Variables are numbered in order of first appearance, and each number is mapped back to its name in a comment line
+// of the form "c var <number> <name>", which ReadDIMACS understands.
+// Cardinality, linear and XOR constraints are expanded with the default encodings, and another cardinality encoding
+// can be chosen by expanding them with ToCNF first. Composite literals have no CNF form, so formulas containing them
+// are rejected.
+func (f ConjunctiveFormula) WriteDIMACS(w io.Writer) error {
+	compiled := f.ToCNF(SequentialCounterEncoding).Compile(NewVariableTable())
+	if composites := compiled.Composites(); len(composites) > 0 {
+		return fmt.Errorf("cannot write composite clause (%s) as DIMACS: expand it into plain clauses first", composites[0])
+	}
+
+	out := bufio.NewWriter(w)
+	variables := compiled.Variables()
+	for v := 0; v < variables.Len(); v++ {
+		fmt.Fprintf(out, "%s%d %s\n", dimacsNamePrefix, v+1, variables.Name(Var(v)))
+	}
+
+	fmt.Fprintf(out, "p cnf %d %d\n", variables.Len(), len(compiled.Clauses()))
+	for _, lits := range compiled.Clauses() {
+		for _, l := range lits {
+			fmt.Fprintf(out, "%d ", dimacsLit(l))
+		}
+		fmt.Fprintln(out, "0")
+	}
+
+	return out.Flush()
+}
+
+// ReadDIMACS reads a formula in DIMACS CNF format.
+// Variables are named by their number, unless a "c var <number> <name>" comment line gives them a name.
+func ReadDIMACS(r io.Reader) (ConjunctiveFormula, error) {
+	names := make(map[int]string)
+	clauses := make([]DisjunctiveClause, 0)
+	literals := make([]Literal, 0)
+	numVariables, numClauses := -1, -1
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "%") {
+			// Some benchmark suites end their files with a "%" line.
+			break
+		}
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "c"):
+			// Comments are free-form, so any that don't name a variable are ignored.
+			if number, name, ok := parseDIMACSName(line); ok {
+				names[number] = name
+			}
+			continue
+		case strings.HasPrefix(line, "p"):
+			fields := strings.Fields(line)
+			if numVariables >= 0 || len(fields) != 4 || fields[1] != "cnf" {
+				return ConjunctiveFormula{}, fmt.Errorf("line %d: malformed problem line %q", lineNumber, line)
+			}
+			var err error
+			if numVariables, err = strconv.Atoi(fields[2]); err != nil {
+				return ConjunctiveFormula{}, fmt.Errorf("line %d: malformed variable count: %v", lineNumber, err)
+			}
+			if numClauses, err = strconv.Atoi(fields[3]); err != nil {
+				return ConjunctiveFormula{}, fmt.Errorf("line %d: malformed clause count: %v", lineNumber, err)
+			}
+			continue
+		}
+
+		if numVariables < 0 {
+			return ConjunctiveFormula{}, fmt.Errorf("line %d: clause before problem line", lineNumber)
+		}
+		for _, field := range strings.Fields(line) {
+			number, err := strconv.Atoi(field)
+			if err != nil {
+				return ConjunctiveFormula{}, fmt.Errorf("line %d: malformed literal %q", lineNumber, field)
+			}
+			if number == 0 {
+				clauses = append(clauses, NewDisjunctiveClause(literals...))
+				literals = make([]Literal, 0)
+				continue
+			}
+
+			variable := number
+			if variable < 0 {
+				variable = -variable
+			}
+			if variable > numVariables {
+				return ConjunctiveFormula{}, fmt.Errorf("line %d: variable %d exceeds declared count %d", lineNumber, variable, numVariables)
+			}
+
+			name, ok := names[variable]
+			if !ok {
+				name = strconv.Itoa(variable)
+			}
+			var literal Literal = NewLiteral(name)
+			if number < 0 {
+				literal = literal.Negate()
+			}
+			literals = append(literals, literal)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ConjunctiveFormula{}, err
+	}
+
+	// Tolerate a missing terminator on the last clause.
+	if len(literals) > 0 {
+		clauses = append(clauses, NewDisjunctiveClause(literals...))
+	}
+	if numVariables < 0 {
+		return ConjunctiveFormula{}, fmt.Errorf("missing problem line")
+	}
+	if len(clauses) != numClauses {
+		return ConjunctiveFormula{}, fmt.Errorf("read %d clauses, but problem line declares %d", len(clauses), numClauses)
+	}
+
+	return NewConjunctiveFormula(clauses), nil
+}
+
+// dimacsLit returns the DIMACS number of a literal. Variables are numbered from 1, and negated literals are negative.
+func dimacsLit(l Lit) int {
+	number := int(l.Var()) + 1
+	if l.Negated() {
+		return -number
+	}
+	return number
+}
+
+// parseDIMACSName parses a "c var <number> <name>" comment line. Returns false if the line doesn't have that form.
+func parseDIMACSName(line string) (int, string, bool) {
+	if !strings.HasPrefix(line, dimacsNamePrefix) {
+		return 0, "", false
+	}
+	fields := strings.SplitN(strings.TrimPrefix(line, dimacsNamePrefix), " ", 2)
+	number, err := strconv.Atoi(fields[0])
+	if err != nil || len(fields) < 2 {
+		return 0, "", false
+	}
+	return number, fields[1], true
+}
@@ -0,0 +1,114 @@
+package sat
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// assignments returns every assignment of the given variables.
+func assignments(names []string) []map[string]bool {
+	result := make([]map[string]bool, 0, 1<<uint(len(names)))
+	for bits := 0; bits < 1<<uint(len(names)); bits++ {
+		state := make(map[string]bool, len(names))
+		for i, name := range names {
+			state[name] = bits&(1<<uint(i)) != 0
+		}
+		result = append(result, state)
+	}
+	return result
+}
+
+func readDIMACSFile(t *testing.T, name string) ConjunctiveFormula {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	formula, err := ReadDIMACS(file)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return formula
+}
+
+func TestSolveDIMACSBenchmarks(t *testing.T) {
+	tests := []struct {
+		file        string
+		satisfiable bool
+	}{
+		{"pigeonhole.cnf", false},
+		{"named.cnf", true},
+	}
+
+	for _, test := range tests {
+		formula := readDIMACSFile(t, test.file)
+		model, ok := Solve(formula, map[string]bool{})
+		if ok != test.satisfiable {
+			t.Errorf("%s: satisfiable = %v, want %v", test.file, ok, test.satisfiable)
+			continue
+		}
+		if ok && formula.Evaluate(model) != true {
+			t.Errorf("%s: model %v doesn't satisfy the formula", test.file, model)
+		}
+	}
+}
+
+func TestReadDIMACSNames(t *testing.T) {
+	formula := readDIMACSFile(t, "named.cnf")
+	model, ok := Solve(formula, map[string]bool{})
+	if !ok {
+		t.Fatal("named.cnf: unsatisfiable")
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if _, ok := model[name]; !ok {
+			t.Errorf("named.cnf: model %v has no variable %q", model, name)
+		}
+	}
+}
+
+func TestReadDIMACSIgnoresFreeFormComments(t *testing.T) {
+	input := "c var names aren't given\nc var\nc variables: 2\np cnf 2 1\n1 -2 0\n"
+	formula, err := ReadDIMACS(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := formula.String(), "(1 v ~2)"; got != want {
+		t.Errorf("formula = %s, want %s", got, want)
+	}
+}
+
+func TestDIMACSRoundTrip(t *testing.T) {
+	names := []string{"x", "y", "cell 1 2"}
+	x, y, cell := NewLiteral(names[0]), NewLiteral(names[1]), NewLiteral(names[2])
+	formula := NewConjunctiveFormula([]DisjunctiveClause{
+		NewDisjunctiveClause(x, y),
+		NewDisjunctiveClause(x.Negate(), cell),
+		NewDisjunctiveClause(y.Negate(), cell.Negate()),
+	})
+
+	var buffer bytes.Buffer
+	if err := formula.WriteDIMACS(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range names {
+		if line := fmt.Sprintf("%s%d %s", dimacsNamePrefix, i+1, name); !strings.Contains(buffer.String(), line+"\n") {
+			t.Errorf("output has no line %q:\n%s", line, buffer.String())
+		}
+	}
+
+	read, err := ReadDIMACS(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, state := range assignments(names) {
+		if got, want := read.Evaluate(state), formula.Evaluate(state); got != want {
+			t.Errorf("%v: read formula evaluates to %v, want %v", state, got, want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package sat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProofFormat is the encoding of a DRAT proof.
+type ProofFormat int
+
+const (
+	// TextProof writes each step on its own line, like "1 -2 0" to add a clause or "d 1 -2 0" to delete one.
+	TextProof ProofFormat = iota
+	// BinaryProof writes each step as an 'a' or 'd' byte, followed by its literals as variable-length integers.
+	// It is much smaller than the text format.
+	BinaryProof
+)
+
+// ProofWriter streams a DRAT proof of unsatisfiability, recording every clause the solver learns and deletes.
+// If the search ends unsatisfiable, the proof ends with the empty clause.
+//
+// Variables are numbered as in WriteDIMACS, so the proof can be checked against the formula's DIMACS form by
+// external tools, as well as by CheckDRAT. A proof is only valid for the formula itself, so searches with an initial
+// state or assumptions don't give a useful proof. Composite literals can't be proved, so formulas containing them
+// fail the proof. Nor can native cardinality, linear or XOR constraints, so SolveWithOptions expands them with the
+// default encodings when writing a proof, as WriteDIMACS and CheckDRAT do.
+type ProofWriter struct {
+	out    *bufio.Writer
+	format ProofFormat
+	err    error
+}
+
+// NewProofWriter creates a proof writer writing to w in the given format.
+func NewProofWriter(w io.Writer, format ProofFormat) *ProofWriter {
+	return &ProofWriter{out: bufio.NewWriter(w), format: format}
+}
+
+// Err returns the first error writing the proof, if any.
+func (p *ProofWriter) Err() error {
+	return p.err
+}
+
+// add records that a clause was derived.
+func (p *ProofWriter) add(lits []Lit) {
+	p.write('a', lits)
+}
+
+// delete records that a clause was removed.
+func (p *ProofWriter) delete(lits []Lit) {
+	p.write('d', lits)
+}
+
+func (p *ProofWriter) write(step byte, lits []Lit) {
+	if p.err != nil {
+		return
+	}
+
+	switch p.format {
+	case BinaryProof:
+		p.out.WriteByte(step)
+		for _, l := range lits {
+			writeProofLit(p.out, l)
+		}
+		p.out.WriteByte(0)
+	default:
+		if step == 'd' {
+			p.out.WriteString("d ")
+		}
+		for _, l := range lits {
+			p.out.WriteString(strconv.Itoa(dimacsLit(l)))
+			p.out.WriteByte(' ')
+		}
+		p.out.WriteString("0\n")
+	}
+}
+
+// writeProofLit writes a literal in the binary format: 2v for literal v and 2v+1 for -v, in 7-bit groups with
+// the high bit set on all but the last.
+func writeProofLit(out *bufio.Writer, l Lit) {
+	n := uint64(l) + 2 // Variables are numbered from 1, and Lit already packs the sign in its low bit.
+	for n >= 0x80 {
+		out.WriteByte(byte(n) | 0x80)
+		n >>= 7
+	}
+	out.WriteByte(byte(n))
+}
+
+// fail stops the proof with an error.
+func (p *ProofWriter) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// flush writes any buffered steps.
+func (p *ProofWriter) flush() {
+	if err := p.out.Flush(); err != nil {
+		p.fail(err)
+	}
+}
+
+// CheckDRAT checks a DRAT proof that the formula is unsatisfiable.
+// Every clause the proof adds must follow from the clauses before it by reverse unit propagation, or be a resolution
+// asymmetric tautology on its first literal, and the proof must derive the empty clause. Deleting clauses that aren't
+// present, and unit clauses, is ignored, as in most checkers.
+// Returns nil if the proof is valid.
+func CheckDRAT(formula ConjunctiveFormula, proof io.Reader, format ProofFormat) error {
+	compiled := formula.ToCNF(SequentialCounterEncoding).Compile(NewVariableTable())
+	if composites := compiled.Composites(); len(composites) > 0 {
+		return fmt.Errorf("cannot check a proof for composite clause (%s)", composites[0])
+	}
+
+	c := &dratChecker{solver: newSolver(EmptyConjunctiveFormula()), stored: make(map[string][]*clause)}
+	c.variables = compiled.Variables()
+	c.grow()
+	for _, lits := range compiled.Clauses() {
+		c.add(lits)
+	}
+	if c.refuted() {
+		return nil
+	}
+
+	steps := readProofSteps(proof, format)
+	for number := 1; ; number++ {
+		step, lits, err := steps()
+		if err == io.EOF {
+			return errors.New("proof does not derive the empty clause")
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: %v", number, err)
+		}
+		c.cover(lits)
+
+		if step == 'd' {
+			c.remove(lits)
+			continue
+		}
+		if !c.implied(lits) && !c.resolutionAsymmetricTautology(lits) {
+			return fmt.Errorf("step %d: clause %v is not implied", number, dimacsLits(lits))
+		}
+		c.add(lits)
+		if len(lits) == 0 || c.refuted() {
+			return nil
+		}
+	}
+}
+
+// dratChecker reuses the solver's propagation to check proof steps.
+// Checking stays at the root level, apart from temporarily assigning the negation of a clause.
+type dratChecker struct {
+	*solver
+	stored map[string][]*clause // The stored clauses for each clause in the proof, by their sorted literals.
+}
+
+// cover extends the variables to cover the given literals, which may be new to the proof.
+func (c *dratChecker) cover(lits []Lit) {
+	for _, l := range lits {
+		for c.variables.Len() <= int(l.Var()) {
+			c.variables.Intern(fmt.Sprintf("%s%d", dratVariablePrefix, c.variables.Len()+1))
+		}
+	}
+	c.grow()
+}
+
+// dratVariablePrefix names variables that only appear in a proof.
+const dratVariablePrefix = "drat#"
+
+// add adds a clause, and propagates any unit it leads to.
+func (c *dratChecker) add(lits []Lit) {
+	if stored := c.addClause(lits); stored != nil {
+		key := proofKey(lits)
+		c.stored[key] = append(c.stored[key], stored)
+	}
+	if c.ok && c.propagate() != nil {
+		c.ok = false
+	}
+}
+
+// remove deletes a clause, if it is stored.
+func (c *dratChecker) remove(lits []Lit) {
+	key := proofKey(lits)
+	stored := c.stored[key]
+	if len(stored) == 0 {
+		return
+	}
+	removed := stored[len(stored)-1]
+	c.stored[key] = stored[:len(stored)-1]
+
+	for i, other := range c.clauses {
+		if other == removed {
+			c.clauses = append(c.clauses[:i], c.clauses[i+1:]...)
+			break
+		}
+	}
+	for _, l := range removed.lits[:2] {
+		watchers := c.watches[l.Not()]
+		for i, other := range watchers {
+			if other == removed {
+				c.watches[l.Not()] = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// refuted returns whether the empty clause follows from the clauses by unit propagation.
+func (c *dratChecker) refuted() bool {
+	return !c.ok
+}
+
+// implied returns whether assigning the negation of a clause leads to a conflict by unit propagation.
+func (c *dratChecker) implied(lits []Lit) bool {
+	c.trailLimits = append(c.trailLimits, len(c.trail))
+	defer c.cancelUntil(0)
+
+	for _, l := range lits {
+		switch c.assigns.Value(l) {
+		case LTrue:
+			return true
+		case LUndef:
+			c.enqueue(l.Not(), nil)
+		}
+	}
+	return c.propagate() != nil
+}
+
+// resolutionAsymmetricTautology returns whether every resolvent of the clause on its first literal is implied, with
+// the stored clauses and with the units assigned at the root.
+func (c *dratChecker) resolutionAsymmetricTautology(lits []Lit) bool {
+	if len(lits) == 0 {
+		return false
+	}
+	pivot := lits[0]
+
+	// Unit clauses are kept as root assignments rather than stored, so a pivot false at the root resolves with the unit
+	// of its negation, leaving the rest of the clause.
+	if c.assigns.Value(pivot) == LFalse && !c.implied(lits[1:]) {
+		return false
+	}
+	for _, other := range c.clauses {
+		contains := false
+		resolvent := append([]Lit(nil), lits...)
+		for _, l := range other.lits {
+			if l == pivot.Not() {
+				contains = true
+			} else {
+				resolvent = append(resolvent, l)
+			}
+		}
+		if contains && !c.implied(resolvent) {
+			return false
+		}
+	}
+	return true
+}
+
+// proofKey identifies a clause regardless of the order of its literals.
+func proofKey(lits []Lit) string {
+	sorted := append([]Lit(nil), lits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return fmt.Sprint(sorted)
+}
+
+// dimacsLits returns the DIMACS numbers of the given literals.
+func dimacsLits(lits []Lit) []int {
+	numbers := make([]int, 0, len(lits))
+	for _, l := range lits {
+		numbers = append(numbers, dimacsLit(l))
+	}
+	return numbers
+}
+
+// readProofSteps returns a function reading the steps of a proof one at a time.
+// Each step is 'a' to add a clause or 'd' to delete one. Returns io.EOF after the last step.
+func readProofSteps(r io.Reader, format ProofFormat) func() (byte, []Lit, error) {
+	in := bufio.NewReader(r)
+	if format == BinaryProof {
+		return func() (byte, []Lit, error) {
+			step, err := in.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			if step != 'a' && step != 'd' {
+				return 0, nil, fmt.Errorf("unknown step %q", step)
+			}
+
+			lits := make([]Lit, 0)
+			for {
+				n, err := readProofNumber(in)
+				if err != nil {
+					return 0, nil, fmt.Errorf("truncated clause: %v", err)
+				}
+				if n == 0 {
+					return step, lits, nil
+				}
+				if n < 2 {
+					return 0, nil, fmt.Errorf("malformed literal %d", n)
+				}
+				lits = append(lits, Lit(n-2))
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return func() (byte, []Lit, error) {
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "c") {
+				continue
+			}
+
+			step := byte('a')
+			if strings.HasPrefix(line, "d") {
+				step = 'd'
+				line = strings.TrimPrefix(line, "d")
+			}
+			lits := make([]Lit, 0)
+			for _, field := range strings.Fields(line) {
+				number, err := strconv.Atoi(field)
+				if err != nil {
+					return 0, nil, fmt.Errorf("malformed literal %q", field)
+				}
+				if number == 0 {
+					return step, lits, nil
+				}
+				if number < 0 {
+					lits = append(lits, NewLit(Var(-number-1), true))
+				} else {
+					lits = append(lits, NewLit(Var(number-1), false))
+				}
+			}
+			return 0, nil, fmt.Errorf("clause %q is missing its terminating 0", line)
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, io.EOF
+	}
+}
+
+// readProofNumber reads a variable-length integer from a binary proof.
+func readProofNumber(in *bufio.Reader) (uint64, error) {
+	var n uint64
+	for shift := uint(0); ; shift += 7 {
+		b, err := in.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		n |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package sat
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// pigeonhole returns a formula asserting that n+1 pigeons sit in n holes, at most one to a hole.
+func pigeonhole(n int) ConjunctiveFormula {
+	sits := func(pigeon, hole int) PositiveLiteral {
+		return NewLiteral(fmt.Sprintf("p%d_%d", pigeon, hole))
+	}
+
+	clauses := make([]DisjunctiveClause, 0)
+	for pigeon := 0; pigeon <= n; pigeon++ {
+		literals := make([]Literal, 0, n)
+		for hole := 0; hole < n; hole++ {
+			literals = append(literals, sits(pigeon, hole))
+		}
+		clauses = append(clauses, NewDisjunctiveClause(literals...))
+	}
+	for hole := 0; hole < n; hole++ {
+		for a := 0; a <= n; a++ {
+			for b := a + 1; b <= n; b++ {
+				clauses = append(clauses, NewDisjunctiveClause(sits(a, hole).Negate(), sits(b, hole).Negate()))
+			}
+		}
+	}
+	return NewConjunctiveFormula(clauses)
+}
+
+// prove solves an unsatisfiable formula, returning its proof.
+func prove(t *testing.T, formula ConjunctiveFormula, format ProofFormat) []byte {
+	t.Helper()
+	var proof bytes.Buffer
+	writer := NewProofWriter(&proof, format)
+	result := SolveWithOptions(formula, map[string]bool{}, Options{Proof: writer})
+	if result.Status != Unsatisfiable {
+		t.Fatalf("status = %s, want UNSAT", result.Status)
+	}
+	if err := writer.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return proof.Bytes()
+}
+
+func TestCheckDRAT(t *testing.T) {
+	formula := pigeonhole(4)
+	for _, format := range []ProofFormat{TextProof, BinaryProof} {
+		proof := prove(t, formula, format)
+		if err := CheckDRAT(formula, bytes.NewReader(proof), format); err != nil {
+			t.Errorf("format %d: valid proof rejected: %v", format, err)
+		}
+	}
+}
+
+func TestCheckDRATRejectsTruncatedProof(t *testing.T) {
+	formula := pigeonhole(4)
+	for _, format := range []ProofFormat{TextProof, BinaryProof} {
+		proof := prove(t, formula, format)
+		if err := CheckDRAT(formula, bytes.NewReader(proof[:len(proof)/2]), format); err == nil {
+			t.Errorf("format %d: truncated proof accepted", format)
+		}
+		if err := CheckDRAT(formula, bytes.NewReader(nil), format); err == nil {
+			t.Errorf("format %d: empty proof accepted", format)
+		}
+	}
+}
+
+func TestCheckDRATRejectsTamperedProof(t *testing.T) {
+	formula := pigeonhole(4)
+
+	// The unit clause p0_0 is neither implied nor a resolution asymmetric tautology.
+	variables := formula.Compile(NewVariableTable()).Variables()
+	v, _ := variables.Lookup("p0_0")
+	tampered := map[ProofFormat][]byte{
+		TextProof:   []byte(fmt.Sprintf("%d 0\n", v+1)),
+		BinaryProof: {'a', byte(2 * (v + 1)), 0},
+	}
+
+	for format, step := range tampered {
+		proof := prove(t, formula, format)
+		if err := CheckDRAT(formula, bytes.NewReader(append(step, proof...)), format); err == nil {
+			t.Errorf("format %d: tampered proof accepted", format)
+		}
+	}
+}
+
+func TestCheckDRATRejectsLemmaFalsifiedByUnits(t *testing.T) {
+	// x = false satisfies the formula, so the unit clause x can't be derived, though adding it refutes the units.
+	formula := NewDisjunctiveClause(NewLiteral("x").Negate()).ToFormula()
+	proofs := map[ProofFormat][]byte{
+		TextProof:   []byte("1 0\n"),
+		BinaryProof: {'a', 2, 0},
+	}
+
+	for format, proof := range proofs {
+		if err := CheckDRAT(formula, bytes.NewReader(proof), format); err == nil {
+			t.Errorf("format %d: proof of a satisfiable formula accepted", format)
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package sat
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+)
+
+// cardinalityPrefix, linearPrefix and xorPrefix start the names of the auxiliary variables that expanded cardinality,
+// linear and XOR constraints introduce.
+const (
+	cardinalityPrefix = "card#"
+	linearPrefix      = "sum#"
+	xorPrefix         = "xor#"
+)
+
+// CardinalityEncoding is a way of expanding cardinality constraints into clauses.
+// Every encoding introduces auxiliary variables, which only appear in the clauses expanding their constraint.
+type CardinalityEncoding int
+
+const (
+	// SequentialCounterEncoding counts the true literals with a unary counter after each literal in turn.
+	// Bounding n literals by k takes O(nk) clauses. This is the default.
+	SequentialCounterEncoding CardinalityEncoding = iota
+	// CommanderEncoding splits the literals into small groups, each with k commander variables that are true for at
+	// least as many of its literals, then bounds the commanders in the same way. It suits small bounds, especially at
+	// most one.
+	CommanderEncoding
+	// TotalizerEncoding sums the literals up a binary tree, with a unary count of the literals below each node.
+	// Counts are cut off past the bound, so bounding n literals by k takes O(nk) variables and O(nk²) clauses.
+	TotalizerEncoding
+	// CardinalityNetworkEncoding sorts the literals in blocks of k with merging networks, keeping only the top k of
+	// each merge. Bounding n literals by k takes O(n log² k) clauses.
+	CardinalityNetworkEncoding
+
+	// binomialEncoding forbids every set of k+1 literals outright. It needs no auxiliary variables, but takes
+	// C(n, k+1) clauses, so is only used internally for small constraints.
+	binomialEncoding CardinalityEncoding = -1
+)
+
+func (e CardinalityEncoding) String() string {
+	switch e {
+	case SequentialCounterEncoding:
+		return "sequential counter"
+	case CommanderEncoding:
+		return "commander"
+	case TotalizerEncoding:
+		return "totalizer"
+	case CardinalityNetworkEncoding:
+		return "cardinality network"
+	case binomialEncoding:
+		return "binomial"
+	default:
+		return fmt.Sprintf("CardinalityEncoding(%d)", int(e))
+	}
+}
+
+// ToCNF returns this formula with its cardinality constraints expanded into clauses with the given encoding, its
+// linear constraints expanded into decision diagrams over their partial sums, and its XOR constraints cut into short
+// XORs that are spelled out.
+// Every model of this formula extends to a model of the expanded formula, and the models of the expanded formula are
+// models of this one once the auxiliary variables are dropped.
+// Auxiliary variables are named "card#", "sum#" or "xor#" followed by a hash of their constraint, so expanding the
+// same constraint twice gives the same clauses.
+func (f ConjunctiveFormula) ToCNF(encoding CardinalityEncoding) ConjunctiveFormula {
+	return f.expand(func(CardinalityConstraint) CardinalityEncoding {
+		return encoding
+	})
+}
+
+// expand expands each cardinality constraint into clauses with the encoding chosen for it, and each linear and XOR
+// constraint in the only way there is for it.
+func (f ConjunctiveFormula) expand(choose func(CardinalityConstraint) CardinalityEncoding) ConjunctiveFormula {
+	if len(f.cardinalities) == 0 && len(f.linears) == 0 && len(f.xors) == 0 {
+		return f
+	}
+
+	clauses := append([]DisjunctiveClause(nil), f.clauses...)
+	for _, c := range f.cardinalities {
+		e := newConstraintEncoder(cardinalityPrefix, c)
+		e.encode(c, choose(c))
+		clauses = append(clauses, e.clauses...)
+	}
+	for _, c := range f.linears {
+		e := newConstraintEncoder(linearPrefix, c)
+		e.encodeLinear(c)
+		clauses = append(clauses, e.clauses...)
+	}
+	for _, c := range f.xors {
+		e := newConstraintEncoder(xorPrefix, c)
+		e.encodeXor(c)
+		clauses = append(clauses, e.clauses...)
+	}
+	return NewConjunctiveFormula(clauses)
+}
+
+// constraintEncoder expands one cardinality, linear or XOR constraint into clauses.
+type constraintEncoder struct {
+	prefix  string // Starts the names of the constraint's auxiliary variables.
+	aux     int    // The number of auxiliary variables so far.
+	clauses []DisjunctiveClause
+}
+
+func newConstraintEncoder(prefix string, c fmt.Stringer) *constraintEncoder {
+	hash := fnv.New64a()
+	hash.Write([]byte(c.String()))
+	return &constraintEncoder{prefix: fmt.Sprintf("%s%x.", prefix, hash.Sum64())}
+}
+
+// fresh returns a new auxiliary variable.
+func (e *constraintEncoder) fresh() Literal {
+	e.aux++
+	return NewLiteral(fmt.Sprintf("%s%d", e.prefix, e.aux))
+}
+
+func (e *constraintEncoder) add(literals ...Literal) {
+	e.clauses = append(e.clauses, NewDisjunctiveClause(literals...))
+}
+
+func (e *constraintEncoder) encode(c CardinalityConstraint, encoding CardinalityEncoding) {
+	n := len(c.literals)
+	if c.max < 0 || c.min > n {
+		e.add()
+		return
+	}
+
+	switch encoding {
+	case SequentialCounterEncoding, CommanderEncoding, binomialEncoding:
+		// These only bound from above, so the lower bound is an upper bound on the negated literals.
+		atMost := e.sequentialCounter
+		switch encoding {
+		case CommanderEncoding:
+			atMost = e.commander
+		case binomialEncoding:
+			atMost = e.binomial
+		}
+		negated := make([]Literal, 0, n)
+		for _, literal := range c.literals {
+			negated = append(negated, literal.Negate())
+		}
+		atMost(c.literals, c.max)
+		atMost(negated, n-c.min)
+	case TotalizerEncoding, CardinalityNetworkEncoding:
+		// These count in unary, so only count as high as the bounds need.
+		limit := c.min
+		if c.max < n && c.max+1 > limit {
+			limit = c.max + 1
+		}
+		if limit <= 0 {
+			return
+		}
+
+		var counts []Literal
+		if encoding == TotalizerEncoding {
+			counts = e.totalizer(c.literals, limit)
+		} else {
+			counts = e.cardinalityNetwork(c.literals, limit)
+		}
+		if c.min > 0 {
+			e.add(counts[c.min-1])
+		}
+		if c.max < n {
+			e.add(counts[c.max].Negate())
+		}
+	default:
+		panic("Unexpected encoding!")
+	}
+}
+
+// sequentialCounter bounds the number of true literals by k, with a register of k variables after each literal
+// counting the true literals so far.
+func (e *constraintEncoder) sequentialCounter(literals []Literal, k int) {
+	n := len(literals)
+	if k >= n {
+		return
+	}
+	if k == 0 {
+		for _, literal := range literals {
+			e.add(literal.Negate())
+		}
+		return
+	}
+
+	// registers[i][j] is true if at least j+1 of the first i+1 literals are true.
+	registers := make([][]Literal, n-1)
+	for i := range registers {
+		for j := 0; j < k; j++ {
+			registers[i] = append(registers[i], e.fresh())
+		}
+	}
+
+	e.add(literals[0].Negate(), registers[0][0])
+	for j := 1; j < k; j++ {
+		e.add(registers[0][j].Negate())
+	}
+	for i := 1; i < n-1; i++ {
+		x, previous, register := literals[i], registers[i-1], registers[i]
+		e.add(x.Negate(), register[0])
+		for j := 0; j < k; j++ {
+			e.add(previous[j].Negate(), register[j])
+		}
+		for j := 1; j < k; j++ {
+			e.add(x.Negate(), previous[j-1].Negate(), register[j])
+		}
+		e.add(x.Negate(), previous[k-1].Negate())
+	}
+	e.add(literals[n-1].Negate(), registers[n-2][k-1].Negate())
+}
+
+// binomial bounds the number of true literals by k, with a clause against every k+1 of them being true.
+func (e *constraintEncoder) binomial(literals []Literal, k int) {
+	if k >= len(literals) {
+		return
+	}
+
+	// chosen holds the negations of the literals chosen so far, from which literal onwards the rest are chosen.
+	var choose func(chosen []Literal, from int)
+	choose = func(chosen []Literal, from int) {
+		if len(chosen) == k+1 {
+			e.add(append([]Literal(nil), chosen...)...)
+			return
+		}
+		for i := from; i <= len(literals)-(k+1-len(chosen)); i++ {
+			choose(append(chosen, literals[i].Negate()), i+1)
+		}
+	}
+	choose(make([]Literal, 0, k+1), 0)
+}
+
+// binomialClauses returns the number of clauses the binomial encoding takes to bound n literals by k, or -1 if it is
+// more than max.
+func binomialClauses(n, k, max int) int {
+	if k >= n {
+		return 0
+	}
+	// C(n, k+1) = C(n, n-k-1), built up one factor at a time. Every partial product is itself a binomial coefficient.
+	r := k + 1
+	if n-r < r {
+		r = n - r
+	}
+	count := 1
+	for i := 1; i <= r; i++ {
+		count = count * (n - r + i) / i
+		if count > max {
+			return -1
+		}
+	}
+	return count
+}
+
+// commander bounds the number of true literals by k. The literals are split into groups of k+2, each with k
+// commanders that must be true for at least as many of its literals, and then the commanders are bounded by k.
+// Few enough literals are bounded directly: pairwise for at most one, and with a sequential counter otherwise.
+func (e *constraintEncoder) commander(literals []Literal, k int) {
+	n := len(literals)
+	if k >= n {
+		return
+	}
+	if n <= 2*k+2 {
+		if k == 1 {
+			for i, a := range literals {
+				for _, b := range literals[i+1:] {
+					e.add(a.Negate(), b.Negate())
+				}
+			}
+			return
+		}
+		e.sequentialCounter(literals, k)
+		return
+	}
+
+	commanders := make([]Literal, 0)
+	for start := 0; start < n; start += k + 2 {
+		end := start + k + 2
+		if end > n {
+			end = n
+		}
+		group := literals[start:end]
+		if len(group) <= k {
+			// The group can't pass the bound alone, so its literals are their own commanders.
+			commanders = append(commanders, group...)
+			continue
+		}
+
+		// At most k of the group and the negated commanders are true, so at least as many commanders as literals are.
+		bounded := append([]Literal(nil), group...)
+		previous := Literal(nil)
+		for j := 0; j < k; j++ {
+			commander := e.fresh()
+			commanders = append(commanders, commander)
+			bounded = append(bounded, commander.Negate())
+			if previous != nil {
+				// Commanders are true in order, to avoid symmetric assignments.
+				e.add(commander.Negate(), previous)
+			}
+			previous = commander
+		}
+		e.commander(bounded, k)
+	}
+	e.commander(commanders, k)
+}
+
+// totalizer returns a unary count of the true literals: the i-th variable is true if at least i+1 literals are.
+// The count stops at limit, so the last variable is true if at least that many literals are.
+func (e *constraintEncoder) totalizer(literals []Literal, limit int) []Literal {
+	n := len(literals)
+	if n == 1 {
+		return literals
+	}
+	left, right := e.totalizer(literals[:n/2], limit), e.totalizer(literals[n/2:], limit)
+
+	size := n
+	if limit < size {
+		size = limit
+	}
+	counts := make([]Literal, 0, size)
+	for t := 0; t < size; t++ {
+		counts = append(counts, e.fresh())
+	}
+
+	for i := 0; i <= len(left); i++ {
+		for j := 0; j <= len(right); j++ {
+			// If i of the left and j of the right are true, at least i+j are.
+			if i+j > 0 {
+				t := i + j
+				if t > size {
+					t = size
+				}
+				clause := []Literal{counts[t-1]}
+				if i > 0 {
+					clause = append(clause, left[i-1].Negate())
+				}
+				if j > 0 {
+					clause = append(clause, right[j-1].Negate())
+				}
+				e.add(clause...)
+			}
+
+			// If at most i of the left and j of the right are true, at most i+j are.
+			if i+j < size {
+				clause := []Literal{counts[i+j].Negate()}
+				if i < len(left) {
+					clause = append(clause, left[i])
+				}
+				if j < len(right) {
+					clause = append(clause, right[j])
+				}
+				e.add(clause...)
+			}
+		}
+	}
+	return counts
+}
+
+// cardinalityNetwork returns a unary count of the true literals, like totalizer, by sorting them with a network
+// whose outputs are true before false. Only the top k outputs of each merge are kept, for the least power of two k
+// that reaches the limit.
+// Wires that are known to be false are nil, and need no comparators.
+func (e *constraintEncoder) cardinalityNetwork(literals []Literal, limit int) []Literal {
+	k := 1
+	for k < limit {
+		k *= 2
+	}
+	wires := append([]Literal(nil), literals...)
+	for len(wires)%k != 0 {
+		wires = append(wires, nil)
+	}
+
+	counts := e.sortingNetwork(wires[:k])
+	for start := k; start < len(wires); start += k {
+		counts = e.simplifiedMerge(counts, e.sortingNetwork(wires[start:start+k]))[:k]
+	}
+	return counts
+}
+
+// sortingNetwork sorts a power of two of wires, true first, by merging sorted halves.
+func (e *constraintEncoder) sortingNetwork(wires []Literal) []Literal {
+	if len(wires) == 1 {
+		return wires
+	}
+	half := len(wires) / 2
+	return e.merge(e.sortingNetwork(wires[:half]), e.sortingNetwork(wires[half:]))
+}
+
+// merge merges two sorted sequences of the same power of two length, with Batcher's odd-even merge.
+func (e *constraintEncoder) merge(a, b []Literal) []Literal {
+	if len(a) == 1 {
+		high, low := e.comparator(a[0], b[0])
+		return []Literal{high, low}
+	}
+
+	odd, even := e.merge(alternate(a, 0), alternate(b, 0)), e.merge(alternate(a, 1), alternate(b, 1))
+	merged := []Literal{odd[0]}
+	for i := 0; i < len(a)-1; i++ {
+		high, low := e.comparator(odd[i+1], even[i])
+		merged = append(merged, high, low)
+	}
+	return append(merged, even[len(a)-1])
+}
+
+// simplifiedMerge returns the top len(a)+1 wires of merging two sorted sequences of the same power of two length.
+// The rest of the merge can't reach the top, so isn't built.
+func (e *constraintEncoder) simplifiedMerge(a, b []Literal) []Literal {
+	if len(a) == 1 {
+		high, low := e.comparator(a[0], b[0])
+		return []Literal{high, low}
+	}
+
+	odd, even := e.simplifiedMerge(alternate(a, 0), alternate(b, 0)), e.simplifiedMerge(alternate(a, 1), alternate(b, 1))
+	merged := []Literal{odd[0]}
+	for i := 0; i < len(a)/2; i++ {
+		high, low := e.comparator(odd[i+1], even[i])
+		merged = append(merged, high, low)
+	}
+	return merged
+}
+
+// comparator returns wires that are true if either or both of the given wires are, respectively.
+func (e *constraintEncoder) comparator(a, b Literal) (Literal, Literal) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+
+	high, low := e.fresh(), e.fresh()
+	e.add(a.Negate(), high)
+	e.add(b.Negate(), high)
+	e.add(a.Negate(), b.Negate(), low)
+	e.add(high.Negate(), a, b)
+	e.add(low.Negate(), a)
+	e.add(low.Negate(), b)
+	return high, low
+}
+
+// alternate returns every other wire, starting from the given index.
+func alternate(wires []Literal, start int) []Literal {
+	alternated := make([]Literal, 0, len(wires)/2)
+	for i := start; i < len(wires); i += 2 {
+		alternated = append(alternated, wires[i])
+	}
+	return alternated
+}
+
+// encodeLinear expands a linear constraint into a decision diagram for each of its bounds.
+func (e *constraintEncoder) encodeLinear(c LinearConstraint) {
+	terms, bounds := c.upperBounds()
+	for i := range terms {
+		memo := make(map[[2]int]interface{})
+		switch root := e.sumDiagram(terms[i], 0, bounds[i], memo).(type) {
+		case bool:
+			if !root {
+				e.add()
+			}
+		case Literal:
+			e.add(root)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+}
+
+// sumDiagram returns a literal that implies the weights of the true terms from the ith on sum to at most k, or a bool
+// if that is decided. Each node branches on one term, and nodes reached along different paths with the same room left
+// are shared. Weights must be positive.
+func (e *constraintEncoder) sumDiagram(terms []WeightedLiteral, i, k int, memo map[[2]int]interface{}) interface{} {
+	if k < 0 {
+		return false
+	}
+	rest := 0
+	for _, term := range terms[i:] {
+		rest += term.weight
+	}
+	if rest <= k {
+		return true
+	}
+	if node, ok := memo[[2]int{i, k}]; ok {
+		return node
+	}
+
+	node := e.fresh()
+	memo[[2]int{i, k}] = node
+	term := terms[i]
+	// If the term is false the rest have the same room, and if it is true they have its weight less.
+	for _, branch := range []struct {
+		condition []Literal
+		k         int
+	}{
+		{nil, k},
+		{[]Literal{term.literal.Negate()}, k - term.weight},
+	} {
+		switch next := e.sumDiagram(terms, i+1, branch.k, memo).(type) {
+		case bool:
+			if !next {
+				e.add(append([]Literal{node.Negate()}, branch.condition...)...)
+			}
+		case Literal:
+			e.add(append([]Literal{node.Negate(), next}, branch.condition...)...)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+	return node
+}
+
+// maxXorLength is the longest XOR spelled out directly when expanding an XOR constraint, in 2^(n-1) clauses.
+const maxXorLength = 4
+
+// encodeXor expands an XOR constraint into clauses. Long XORs are cut into pieces, each summing its literals into an
+// auxiliary variable that takes their place in the rest.
+func (e *constraintEncoder) encodeXor(c XorConstraint) {
+	names, odd := c.normalize()
+	literals := make([]Literal, 0, len(names))
+	for _, name := range names {
+		literals = append(literals, NewLiteral(name))
+	}
+
+	for len(literals) > maxXorLength {
+		// The piece's literals XOR the auxiliary variable is even, so the variable is their sum.
+		sum := e.fresh()
+		e.parity(append(literals[:maxXorLength-1:maxXorLength-1], sum), false)
+		literals = append([]Literal{sum}, literals[maxXorLength-1:]...)
+	}
+	e.parity(literals, odd)
+}
+
+// parity adds a clause ruling out each assignment to the literals with the wrong parity.
+func (e *constraintEncoder) parity(literals []Literal, odd bool) {
+	for assignment := 0; assignment < 1<<uint(len(literals)); assignment++ {
+		if (bits.OnesCount(uint(assignment))%2 == 1) == odd {
+			continue
+		}
+		clause := make([]Literal, 0, len(literals))
+		for i, literal := range literals {
+			if assignment>>uint(i)&1 == 1 {
+				clause = append(clause, literal.Negate())
+			} else {
+				clause = append(clause, literal)
+			}
+		}
+		e.add(clause...)
+	}
+}
@@ -0,0 +1,585 @@
+package sat
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// AndExpression is true if all of its operands are true.
+type AndExpression struct {
+	operands []Expression
+}
+
+// And creates an expression that is true if all of the operands are true. With no operands, it is true.
+func And(operands ...Expression) AndExpression {
+	return AndExpression{operands}
+}
+
+// Operands returns the operands of this expression.
+func (e AndExpression) Operands() []Expression {
+	return e.operands
+}
+
+// Evaluate evaluates this expression, returning a simplified expression or a bool.
+func (e AndExpression) Evaluate(state map[string]bool) interface{} {
+	remaining := make([]Expression, 0, len(e.operands))
+	for _, operand := range e.operands {
+		switch value := operand.Evaluate(state).(type) {
+		case bool:
+			if !value {
+				return false
+			}
+		case Expression:
+			remaining = append(remaining, value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+	if len(remaining) == 0 {
+		return true
+	}
+	return AndExpression{remaining}
+}
+
+func (e AndExpression) String() string {
+	return joinOperands(e.operands, " ^ ", "true")
+}
+
+// OrExpression is true if any of its operands is true.
+type OrExpression struct {
+	operands []Expression
+}
+
+// Or creates an expression that is true if any of the operands is true. With no operands, it is false.
+func Or(operands ...Expression) OrExpression {
+	return OrExpression{operands}
+}
+
+// Operands returns the operands of this expression.
+func (e OrExpression) Operands() []Expression {
+	return e.operands
+}
+
+// Evaluate evaluates this expression, returning a simplified expression or a bool.
+func (e OrExpression) Evaluate(state map[string]bool) interface{} {
+	remaining := make([]Expression, 0, len(e.operands))
+	for _, operand := range e.operands {
+		switch value := operand.Evaluate(state).(type) {
+		case bool:
+			if value {
+				return true
+			}
+		case Expression:
+			remaining = append(remaining, value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+	if len(remaining) == 0 {
+		return false
+	}
+	return OrExpression{remaining}
+}
+
+func (e OrExpression) String() string {
+	return joinOperands(e.operands, " v ", "false")
+}
+
+// NotExpression is true if its operand is false.
+type NotExpression struct {
+	operand Expression
+}
+
+// Not creates an expression that is true if the operand is false.
+func Not(operand Expression) NotExpression {
+	return NotExpression{operand}
+}
+
+// Operand returns the negated expression.
+func (e NotExpression) Operand() Expression {
+	return e.operand
+}
+
+// Evaluate evaluates this expression, returning a simplified expression or a bool.
+func (e NotExpression) Evaluate(state map[string]bool) interface{} {
+	switch value := e.operand.Evaluate(state).(type) {
+	case bool:
+		return !value
+	case Expression:
+		return NotExpression{value}
+	default:
+		panic("Unexpected type!")
+	}
+}
+
+func (e NotExpression) String() string {
+	return fmt.Sprintf("~%s", e.operand)
+}
+
+// ImpliesExpression is true if its premise is false or its conclusion is true.
+type ImpliesExpression struct {
+	premise, conclusion Expression
+}
+
+// Implies creates an expression that is true if the premise is false or the conclusion is true.
+func Implies(premise, conclusion Expression) ImpliesExpression {
+	return ImpliesExpression{premise, conclusion}
+}
+
+// Operands returns the premise and conclusion of this expression.
+func (e ImpliesExpression) Operands() (premise, conclusion Expression) {
+	return e.premise, e.conclusion
+}
+
+// Evaluate evaluates this expression, returning a simplified expression or a bool.
+func (e ImpliesExpression) Evaluate(state map[string]bool) interface{} {
+	return OrExpression{[]Expression{NotExpression{e.premise}, e.conclusion}}.Evaluate(state)
+}
+
+func (e ImpliesExpression) String() string {
+	return fmt.Sprintf("(%s -> %s)", e.premise, e.conclusion)
+}
+
+// IffExpression is true if its operands are both true or both false.
+type IffExpression struct {
+	a, b Expression
+}
+
+// Iff creates an expression that is true if the operands are equal.
+func Iff(a, b Expression) IffExpression {
+	return IffExpression{a, b}
+}
+
+// Operands returns the operands of this expression.
+func (e IffExpression) Operands() (a, b Expression) {
+	return e.a, e.b
+}
+
+// Evaluate evaluates this expression, returning a simplified expression or a bool.
+func (e IffExpression) Evaluate(state map[string]bool) interface{} {
+	return NotExpression{XorExpression{[]Expression{e.a, e.b}}}.Evaluate(state)
+}
+
+func (e IffExpression) String() string {
+	return fmt.Sprintf("(%s <-> %s)", e.a, e.b)
+}
+
+// XorExpression is true if an odd number of its operands are true.
+type XorExpression struct {
+	operands []Expression
+}
+
+// Xor creates an expression that is true if an odd number of the operands are true. With no operands, it is false.
+func Xor(operands ...Expression) XorExpression {
+	return XorExpression{operands}
+}
+
+// Operands returns the operands of this expression.
+func (e XorExpression) Operands() []Expression {
+	return e.operands
+}
+
+// Evaluate evaluates this expression, returning a simplified expression or a bool.
+func (e XorExpression) Evaluate(state map[string]bool) interface{} {
+	remaining := make([]Expression, 0, len(e.operands))
+	parity := false
+	for _, operand := range e.operands {
+		switch value := operand.Evaluate(state).(type) {
+		case bool:
+			parity = parity != value
+		case Expression:
+			remaining = append(remaining, value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+	if len(remaining) == 0 {
+		return parity
+	}
+	if parity {
+		// Flipping one operand flips the parity back.
+		remaining[0] = NotExpression{remaining[0]}
+	}
+	return XorExpression{remaining}
+}
+
+func (e XorExpression) String() string {
+	return joinOperands(e.operands, " xor ", "false")
+}
+
+// ITEExpression takes the value of one of two expressions, depending on a condition.
+type ITEExpression struct {
+	condition, then, otherwise Expression
+}
+
+// ITE creates an expression that takes the value of then if the condition is true, and of otherwise if it is false.
+func ITE(condition, then, otherwise Expression) ITEExpression {
+	return ITEExpression{condition, then, otherwise}
+}
+
+// Operands returns the condition and the two expressions chosen between.
+func (e ITEExpression) Operands() (condition, then, otherwise Expression) {
+	return e.condition, e.then, e.otherwise
+}
+
+// Evaluate evaluates this expression, returning a simplified expression or a bool.
+func (e ITEExpression) Evaluate(state map[string]bool) interface{} {
+	switch value := e.condition.Evaluate(state).(type) {
+	case bool:
+		if value {
+			return e.then.Evaluate(state)
+		}
+		return e.otherwise.Evaluate(state)
+	case Expression:
+		// Either way, one of the branches holds along with its side of the condition.
+		return OrExpression{[]Expression{
+			AndExpression{[]Expression{value, e.then}},
+			AndExpression{[]Expression{NotExpression{value}, e.otherwise}},
+		}}.Evaluate(state)
+	default:
+		panic("Unexpected type!")
+	}
+}
+
+func (e ITEExpression) String() string {
+	return fmt.Sprintf("(if %s then %s else %s)", e.condition, e.then, e.otherwise)
+}
+
+// joinOperands formats operands joined by an operator, or as the operator's identity if there are none.
+func joinOperands(operands []Expression, operator string, identity string) string {
+	if len(operands) == 0 {
+		return identity
+	}
+	strs := make([]string, 0, len(operands))
+	for _, operand := range operands {
+		strs = append(strs, fmt.Sprintf("%s", operand))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(strs, operator))
+}
+
+// expressionPrefix starts the names of the auxiliary variables that converted expressions introduce.
+const expressionPrefix = "expr#"
+
+// ExpressionEncoding is a way of converting expressions into clauses.
+// Both encodings give each compound subexpression an auxiliary variable defined by clauses over its operands'
+// variables, so the clauses grow linearly with the expression, unlike distributing ORs over ANDs.
+type ExpressionEncoding int
+
+const (
+	// PlaistedGreenbaumEncoding only defines each auxiliary variable in the direction its context needs: a
+	// subexpression that only has to hold if its variable is true only gets clauses for that. This is the default.
+	PlaistedGreenbaumEncoding ExpressionEncoding = iota
+	// TseitinEncoding defines each auxiliary variable as equivalent to its subexpression, so every model of the
+	// expression extends to exactly one model of the clauses.
+	TseitinEncoding
+)
+
+func (e ExpressionEncoding) String() string {
+	switch e {
+	case PlaistedGreenbaumEncoding:
+		return "Plaisted-Greenbaum"
+	case TseitinEncoding:
+		return "Tseitin"
+	default:
+		return fmt.Sprintf("ExpressionEncoding(%d)", int(e))
+	}
+}
+
+// ExpressionToCNF converts an expression into a formula with the given encoding.
+// Every model of the expression extends to a model of the formula, and the models of the formula are models of the
+// expression once the auxiliary variables are dropped. Auxiliary variables are named "expr#" followed by a hash of
+// the operation they stand for, so the same subexpression shares a variable wherever it appears, even across
+// conversions.
+func ExpressionToCNF(e Expression, encoding ExpressionEncoding) ConjunctiveFormula {
+	c := &expressionConverter{encoding: encoding, defined: make(map[string]polarity)}
+	c.assert(e)
+	return NewConjunctiveFormula(c.clauses)
+}
+
+// polarity is the set of directions an auxiliary variable must be defined in: positive if the subexpression must
+// hold when the variable is true, and negative if the variable must be true when the subexpression holds.
+type polarity uint8
+
+const (
+	positive polarity = 1 << iota
+	negative
+	bothPolarities = positive | negative
+)
+
+func (p polarity) flip() polarity {
+	return p&positive<<1 | p&negative>>1
+}
+
+// expressionConverter converts expressions into clauses.
+type expressionConverter struct {
+	encoding ExpressionEncoding
+	defined  map[string]polarity // The directions each auxiliary variable has been defined in so far.
+	clauses  []DisjunctiveClause
+}
+
+func (c *expressionConverter) add(literals ...Literal) {
+	c.clauses = append(c.clauses, NewDisjunctiveClause(literals...))
+}
+
+// assert adds clauses that hold exactly when the expression is true. Conjunctions and disjunctions at the top need
+// no auxiliary variables of their own.
+func (c *expressionConverter) assert(e Expression) {
+	switch expression := e.(type) {
+	case AndExpression:
+		for _, operand := range expression.operands {
+			c.assert(operand)
+		}
+	case OrExpression:
+		c.assertClause(expression.operands, false)
+	case ImpliesExpression:
+		c.assertClause([]Expression{NotExpression{expression.premise}, expression.conclusion}, false)
+	case NotExpression:
+		switch negated := expression.operand.(type) {
+		case NotExpression:
+			c.assert(negated.operand)
+		case OrExpression:
+			for _, operand := range negated.operands {
+				c.assert(NotExpression{operand})
+			}
+		case AndExpression:
+			c.assertClause(negated.operands, true)
+		default:
+			c.assertValue(c.convert(e, positive))
+		}
+	default:
+		c.assertValue(c.convert(e, positive))
+	}
+}
+
+// assertClause adds a clause that at least one of the operands is true, or false if negated.
+func (c *expressionConverter) assertClause(operands []Expression, negated bool) {
+	literals := make([]Literal, 0, len(operands))
+	for _, operand := range operands {
+		if negated {
+			operand = NotExpression{operand}
+		}
+		switch value := c.convert(operand, positive).(type) {
+		case bool:
+			if value {
+				return
+			}
+		case Literal:
+			literals = append(literals, value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+	c.add(literals...)
+}
+
+// assertValue adds a clause that a converted expression is true.
+func (c *expressionConverter) assertValue(value interface{}) {
+	switch value := value.(type) {
+	case bool:
+		if !value {
+			c.add()
+		}
+	case Literal:
+		c.add(value)
+	default:
+		panic("Unexpected type!")
+	}
+}
+
+// convert returns a literal standing for an expression, defined in the given directions, or a bool if the expression
+// is constant.
+func (c *expressionConverter) convert(e Expression, p polarity) interface{} {
+	if c.encoding == TseitinEncoding {
+		p = bothPolarities
+	}
+
+	switch expression := e.(type) {
+	case Literal:
+		return expression
+	case NotExpression:
+		return negateValue(c.convert(expression.operand, p.flip()))
+	case AndExpression:
+		return c.and(c.convertAll(expression.operands, p), p)
+	case OrExpression:
+		return c.or(c.convertAll(expression.operands, p), p)
+	case ImpliesExpression:
+		return c.or([]interface{}{c.convert(NotExpression{expression.premise}, p), c.convert(expression.conclusion, p)}, p)
+	case IffExpression:
+		return negateValue(c.xor(c.convertAll([]Expression{expression.a, expression.b}, bothPolarities)))
+	case XorExpression:
+		return c.xor(c.convertAll(expression.operands, bothPolarities))
+	case ITEExpression:
+		condition := c.convert(expression.condition, bothPolarities)
+		then, otherwise := c.convert(expression.then, p), c.convert(expression.otherwise, p)
+		return c.ite(condition, then, otherwise, p)
+	default:
+		panic(fmt.Sprintf("Unknown expression type: %T", e))
+	}
+}
+
+func (c *expressionConverter) convertAll(operands []Expression, p polarity) []interface{} {
+	values := make([]interface{}, 0, len(operands))
+	for _, operand := range operands {
+		values = append(values, c.convert(operand, p))
+	}
+	return values
+}
+
+// and returns a literal standing for the conjunction of converted operands, or a bool if it is constant.
+func (c *expressionConverter) and(values []interface{}, p polarity) interface{} {
+	literals := make([]Literal, 0, len(values))
+	for _, value := range values {
+		switch value := value.(type) {
+		case bool:
+			if !value {
+				return false
+			}
+		case Literal:
+			literals = append(literals, value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+	switch len(literals) {
+	case 0:
+		return true
+	case 1:
+		return literals[0]
+	}
+
+	x, needed := c.define("and", literals, p)
+	if needed&positive != 0 {
+		for _, literal := range literals {
+			c.add(x.Negate(), literal)
+		}
+	}
+	if needed&negative != 0 {
+		c.add(append([]Literal{x}, negateAll(literals)...)...)
+	}
+	return x
+}
+
+// or returns a literal standing for the disjunction of converted operands, or a bool if it is constant.
+func (c *expressionConverter) or(values []interface{}, p polarity) interface{} {
+	negated := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		negated = append(negated, negateValue(value))
+	}
+	// A OR B = NOT (NOT A AND NOT B)
+	return negateValue(c.and(negated, p.flip()))
+}
+
+// xor returns a literal standing for the parity of converted operands, or a bool if it is constant.
+// Operands are combined in a chain of two-input gates. Each gate's operands must be defined both ways, since flipping
+// either flips the result, so the gates are too.
+func (c *expressionConverter) xor(values []interface{}) interface{} {
+	var result interface{} = false
+	for _, value := range values {
+		switch value := value.(type) {
+		case bool:
+			if value {
+				result = negateValue(result)
+			}
+		case Literal:
+			switch acc := result.(type) {
+			case bool:
+				if acc {
+					result = value.Negate()
+				} else {
+					result = value
+				}
+			case Literal:
+				result = c.xorGate(acc, value)
+			}
+		default:
+			panic("Unexpected type!")
+		}
+	}
+	return result
+}
+
+// xorGate returns a literal standing for a XOR b.
+func (c *expressionConverter) xorGate(a, b Literal) Literal {
+	x, needed := c.define("xor", []Literal{a, b}, bothPolarities)
+	if needed&positive != 0 {
+		c.add(x.Negate(), a, b)
+		c.add(x.Negate(), a.Negate(), b.Negate())
+	}
+	if needed&negative != 0 {
+		c.add(x, a.Negate(), b)
+		c.add(x, a, b.Negate())
+	}
+	return x
+}
+
+// ite returns a literal standing for if condition then then else otherwise, or a bool if it is constant.
+func (c *expressionConverter) ite(condition, then, otherwise interface{}, p polarity) interface{} {
+	if value, ok := condition.(bool); ok {
+		if value {
+			return then
+		}
+		return otherwise
+	}
+	if value, ok := then.(bool); ok {
+		if value {
+			return c.or([]interface{}{condition, otherwise}, p)
+		}
+		return c.and([]interface{}{negateValue(condition), otherwise}, p)
+	}
+	if value, ok := otherwise.(bool); ok {
+		if value {
+			return c.or([]interface{}{negateValue(condition), then}, p)
+		}
+		return c.and([]interface{}{condition, then}, p)
+	}
+
+	cond, t, e := condition.(Literal), then.(Literal), otherwise.(Literal)
+	x, needed := c.define("ite", []Literal{cond, t, e}, p)
+	if needed&positive != 0 {
+		c.add(x.Negate(), cond.Negate(), t)
+		c.add(x.Negate(), cond, e)
+	}
+	if needed&negative != 0 {
+		c.add(x, cond.Negate(), t.Negate())
+		c.add(x, cond, e.Negate())
+	}
+	return x
+}
+
+// define returns the auxiliary variable standing for an operation on literals, and the directions it still needs
+// defining in, which are then taken as defined.
+func (c *expressionConverter) define(operation string, literals []Literal, p polarity) (Literal, polarity) {
+	strs := make([]string, 0, len(literals))
+	for _, literal := range literals {
+		strs = append(strs, fmt.Sprintf("%s", literal))
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(fmt.Sprintf("%s(%s)", operation, strings.Join(strs, ","))))
+	name := fmt.Sprintf("%s%x", expressionPrefix, hash.Sum64())
+
+	needed := p &^ c.defined[name]
+	c.defined[name] |= p
+	return NewLiteral(name), needed
+}
+
+// negateValue negates a converted expression.
+func negateValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case bool:
+		return !value
+	case Literal:
+		return value.Negate()
+	default:
+		panic("Unexpected type!")
+	}
+}
+
+// negateAll negates each of the literals.
+func negateAll(literals []Literal) []Literal {
+	negated := make([]Literal, 0, len(literals))
+	for _, literal := range literals {
+		negated = append(negated, literal.Negate())
+	}
+	return negated
+}
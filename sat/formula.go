@@ -6,15 +6,19 @@ import (
 )
 
 // ConjunctiveFormula represents a boolean formula in conjunctive normal form.
+// As well as clauses, it can hold cardinality, linear and XOR constraints, which ToCNF expands into clauses.
 type ConjunctiveFormula struct {
-	clauses []DisjunctiveClause
+	clauses       []DisjunctiveClause
+	cardinalities []CardinalityConstraint
+	linears       []LinearConstraint
+	xors          []XorConstraint
 }
 
 // EmptyConjunctiveFormula returns an empty conjunctive formula.
 // This evaluates to true.
 func EmptyConjunctiveFormula() ConjunctiveFormula {
 	clauses := make([]DisjunctiveClause, 0)
-	return ConjunctiveFormula{clauses}
+	return ConjunctiveFormula{clauses: clauses}
 }
 
 // NewConjunctiveFormula creates a formula with the given clauses.
@@ -40,25 +44,75 @@ func (f ConjunctiveFormula) Evaluate(state map[string]bool) interface{} {
 		}
 	}
 
-	if len(remainingClauses) == 0 {
+	remainingCardinalities := make([]CardinalityConstraint, 0)
+	for _, constraint := range f.cardinalities {
+		switch value := constraint.Evaluate(state).(type) {
+		case CardinalityConstraint:
+			remainingCardinalities = append(remainingCardinalities, value)
+		case bool:
+			if !value {
+				return false
+			}
+		default:
+			panic("Unexpected type!")
+		}
+	}
+
+	remainingLinears := make([]LinearConstraint, 0)
+	for _, constraint := range f.linears {
+		switch value := constraint.Evaluate(state).(type) {
+		case LinearConstraint:
+			remainingLinears = append(remainingLinears, value)
+		case bool:
+			if !value {
+				return false
+			}
+		default:
+			panic("Unexpected type!")
+		}
+	}
+
+	remainingXors := make([]XorConstraint, 0)
+	for _, constraint := range f.xors {
+		switch value := constraint.Evaluate(state).(type) {
+		case XorConstraint:
+			remainingXors = append(remainingXors, value)
+		case bool:
+			if !value {
+				return false
+			}
+		default:
+			panic("Unexpected type!")
+		}
+	}
+
+	if len(remainingClauses) == 0 && len(remainingCardinalities) == 0 && len(remainingLinears) == 0 &&
+		len(remainingXors) == 0 {
 		return true
 	}
 
-	return ConjunctiveFormula{remainingClauses}
+	return ConjunctiveFormula{remainingClauses, remainingCardinalities, remainingLinears, remainingXors}
 }
 
 // And returns a formula that also contains the clauses in the other formula.
 func (f ConjunctiveFormula) And(other ConjunctiveFormula) ConjunctiveFormula {
-	return ConjunctiveFormula{append(f.clauses, other.clauses...)}
+	return ConjunctiveFormula{
+		append(f.clauses, other.clauses...),
+		append(f.cardinalities, other.cardinalities...),
+		append(f.linears, other.linears...),
+		append(f.xors, other.xors...),
+	}
 }
 
 // Or returns this formula ORed with the other formulas, in CNF.
+// Cardinality, linear and XOR constraints can't be distributed over, so they are expanded into clauses with the
+// default encodings.
 func (f ConjunctiveFormula) Or(others ...ConjunctiveFormula) ConjunctiveFormula {
 	if len(others) == 0 {
 		return f
 	}
 
-	other := others[0]
+	f, other := f.ToCNF(SequentialCounterEncoding), others[0].ToCNF(SequentialCounterEncoding)
 	clauses := make([]DisjunctiveClause, 0)
 	for _, fClause := range f.clauses {
 		for _, oClause := range other.clauses {
@@ -67,7 +121,22 @@ func (f ConjunctiveFormula) Or(others ...ConjunctiveFormula) ConjunctiveFormula
 		}
 	}
 
-	return ConjunctiveFormula{clauses}.Or(others[1:]...)
+	return ConjunctiveFormula{clauses: clauses}.Or(others[1:]...)
+}
+
+// Cardinalities returns the cardinality constraints in this formula.
+func (f ConjunctiveFormula) Cardinalities() []CardinalityConstraint {
+	return f.cardinalities
+}
+
+// Linears returns the linear constraints in this formula.
+func (f ConjunctiveFormula) Linears() []LinearConstraint {
+	return f.linears
+}
+
+// Xors returns the XOR constraints in this formula.
+func (f ConjunctiveFormula) Xors() []XorConstraint {
+	return f.xors
 }
 
 func (f ConjunctiveFormula) String() string {
@@ -75,6 +144,15 @@ func (f ConjunctiveFormula) String() string {
 	for _, clause := range f.clauses {
 		strs = append(strs, fmt.Sprintf("(%s)", clause))
 	}
+	for _, constraint := range f.cardinalities {
+		strs = append(strs, fmt.Sprintf("(%s)", constraint))
+	}
+	for _, constraint := range f.linears {
+		strs = append(strs, fmt.Sprintf("(%s)", constraint))
+	}
+	for _, constraint := range f.xors {
+		strs = append(strs, fmt.Sprintf("(%s)", constraint))
+	}
 	return strings.Join(strs, " ^ ")
 }
 
@@ -121,7 +199,7 @@ func (f DisjunctiveFormula) Or(other DisjunctiveFormula) DisjunctiveFormula {
 // ToCNF converts this formula to conjunctive normal form.
 func (f DisjunctiveFormula) ToCNF() ConjunctiveFormula {
 	if len(f.clauses) == 0 {
-		return ConjunctiveFormula{[]DisjunctiveClause{NewDisjunctiveClause()}}
+		return ConjunctiveFormula{clauses: []DisjunctiveClause{NewDisjunctiveClause()}}
 	}
 
 	formulas := make([]ConjunctiveFormula, 0)
@@ -0,0 +1,384 @@
+package sat
+
+import "math/rand"
+
+// BranchingHeuristic chooses the variable to branch on when propagation stalls.
+// Heuristics hold search state, so an instance shouldn't be shared between searches.
+type BranchingHeuristic interface {
+	// Reset prepares the heuristic for a search over the given variables and original clauses.
+	// It is called before every search on a solver, and may keep what it learned from earlier searches.
+	Reset(variables *VariableTable, clauses [][]Lit)
+	// Select returns an unassigned variable to branch on, or false if every variable is assigned.
+	Select(assignment Assignment) (Var, bool)
+	// Learned is called with each clause learned from a conflict.
+	Learned(clause []Lit)
+	// Unassigned is called when backtracking unassigns a variable.
+	Unassigned(v Var)
+}
+
+// Phase selects the value tried first for a branching variable.
+type Phase int
+
+const (
+	// PhaseTrue tries true first. Setting a cell value to true has a lot of downstream propagation.
+	PhaseTrue Phase = iota
+	// PhaseFalse tries false first.
+	PhaseFalse
+	// PhaseSaving tries the value the variable last had, or true if it hasn't been assigned.
+	PhaseSaving
+)
+
+// firstUnassigned returns the lowest unassigned variable.
+func firstUnassigned(assignment Assignment) (Var, bool) {
+	for v, value := range assignment {
+		if value == LUndef {
+			return Var(v), true
+		}
+	}
+	return 0, false
+}
+
+// VSIDS is the variable state independent decaying sum heuristic.
+// Every variable has an activity, which is bumped whenever the variable appears in a learned clause. Activities decay
+// over time, so the heuristic favors variables involved in recent conflicts.
+type VSIDS struct {
+	decay     float64
+	increment float64
+	activity  []float64
+	heap      activityHeap
+	random    *rand.Rand // Breaks ties between initial activities, if set.
+}
+
+// NewVSIDS creates a VSIDS heuristic with the given decay factor, which must be in (0, 1).
+// Smaller factors forget older conflicts faster. Other factors use the default of 0.95.
+func NewVSIDS(decay float64) *VSIDS {
+	if decay <= 0 || decay >= 1 {
+		decay = 0.95
+	}
+	return &VSIDS{decay: decay, increment: 1}
+}
+
+// NewRandomizedVSIDS creates a VSIDS heuristic whose initial activities are tiny random values, so that searches with
+// different seeds start from different branching orders. This is useful for running several searches at once.
+func NewRandomizedVSIDS(decay float64, seed int64) *VSIDS {
+	h := NewVSIDS(decay)
+	h.random = rand.New(rand.NewSource(seed))
+	return h
+}
+
+// Reset prepares the heuristic for a search over the given variables.
+// Activities are kept for variables the heuristic already knows.
+func (h *VSIDS) Reset(variables *VariableTable, clauses [][]Lit) {
+	for len(h.activity) < variables.Len() {
+		activity := 0.0
+		if h.random != nil {
+			activity = h.random.Float64() * 1e-3
+		}
+		h.activity = append(h.activity, activity)
+	}
+	h.heap.activity = h.activity
+	for len(h.heap.positions) < len(h.activity) {
+		h.heap.positions = append(h.heap.positions, -1)
+	}
+	for v := range h.activity {
+		h.heap.push(Var(v))
+	}
+}
+
+// Select returns the unassigned variable with the highest activity.
+func (h *VSIDS) Select(assignment Assignment) (Var, bool) {
+	for !h.heap.empty() {
+		if v := h.heap.pop(); assignment[v] == LUndef {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Learned bumps the activity of the variables in a learned clause, then decays every activity.
+func (h *VSIDS) Learned(clause []Lit) {
+	for _, l := range clause {
+		v := l.Var()
+		h.activity[v] += h.increment
+		if h.activity[v] > 1e100 {
+			// Rescale to avoid overflow. This preserves the order of activities.
+			for i := range h.activity {
+				h.activity[i] *= 1e-100
+			}
+			h.increment *= 1e-100
+		}
+		h.heap.update(v)
+	}
+
+	// Rather than decaying every activity, grow the increment.
+	h.increment /= h.decay
+}
+
+// Unassigned makes a variable available for selection again.
+func (h *VSIDS) Unassigned(v Var) {
+	h.heap.push(v)
+}
+
+// activityHeap is a max-heap of variables, ordered by activity.
+type activityHeap struct {
+	activity  []float64
+	vars      []Var
+	positions []int // The index of each variable in vars, or -1 if it isn't in the heap.
+}
+
+func (h *activityHeap) empty() bool {
+	return len(h.vars) == 0
+}
+
+// push adds a variable to the heap, if it isn't already there.
+func (h *activityHeap) push(v Var) {
+	if h.positions[v] >= 0 {
+		return
+	}
+	h.vars = append(h.vars, v)
+	h.positions[v] = len(h.vars) - 1
+	h.up(len(h.vars) - 1)
+}
+
+// pop removes and returns the variable with the highest activity.
+func (h *activityHeap) pop() Var {
+	top := h.vars[0]
+	last := len(h.vars) - 1
+	h.swap(0, last)
+	h.vars = h.vars[:last]
+	h.positions[top] = -1
+	if last > 0 {
+		h.down(0)
+	}
+	return top
+}
+
+// update restores the heap order after a variable's activity increases.
+func (h *activityHeap) update(v Var) {
+	if i := h.positions[v]; i >= 0 {
+		h.up(i)
+	}
+}
+
+func (h *activityHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h.activity[h.vars[parent]] >= h.activity[h.vars[i]] {
+			return
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+func (h *activityHeap) down(i int) {
+	for {
+		largest := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(h.vars) && h.activity[h.vars[child]] > h.activity[h.vars[largest]] {
+				largest = child
+			}
+		}
+		if largest == i {
+			return
+		}
+		h.swap(i, largest)
+		i = largest
+	}
+}
+
+func (h *activityHeap) swap(i, j int) {
+	h.vars[i], h.vars[j] = h.vars[j], h.vars[i]
+	h.positions[h.vars[i]] = i
+	h.positions[h.vars[j]] = j
+}
+
+// ShortestClause branches on the first unassigned variable of the shortest unsatisfied clause.
+type ShortestClause struct {
+	clauses [][]Lit
+}
+
+// NewShortestClause creates a ShortestClause heuristic.
+func NewShortestClause() *ShortestClause {
+	return &ShortestClause{}
+}
+
+// Reset records the clauses to choose from.
+func (h *ShortestClause) Reset(variables *VariableTable, clauses [][]Lit) {
+	h.clauses = clauses
+}
+
+// Select returns the first unassigned variable of the shortest unsatisfied clause.
+// Variables outside the clauses are selected once every clause is satisfied.
+func (h *ShortestClause) Select(assignment Assignment) (Var, bool) {
+	var best Var
+	minLength := 0
+clauses:
+	for _, c := range h.clauses {
+		length := 0
+		var first Var
+		for _, l := range c {
+			switch assignment.Value(l) {
+			case LTrue:
+				continue clauses
+			case LUndef:
+				if length == 0 {
+					first = l.Var()
+				}
+				length++
+			}
+		}
+
+		if length > 0 && (minLength == 0 || length < minLength) {
+			best = first
+			minLength = length
+		}
+	}
+	if minLength > 0 {
+		return best, true
+	}
+	return firstUnassigned(assignment)
+}
+
+// Learned does nothing.
+func (h *ShortestClause) Learned([]Lit) {}
+
+// Unassigned does nothing.
+func (h *ShortestClause) Unassigned(Var) {}
+
+// MostFrequent branches on the variable appearing most often in unsatisfied clauses.
+type MostFrequent struct {
+	positiveOnly bool
+	clauses      [][]Lit
+	frequency    []int
+}
+
+// NewMostFrequentLiteral creates a MostFrequent heuristic that counts every literal, biased towards positive ones.
+func NewMostFrequentLiteral() *MostFrequent {
+	return &MostFrequent{}
+}
+
+// NewMostFrequentPositiveLiteral creates a MostFrequent heuristic that only counts positive literals.
+func NewMostFrequentPositiveLiteral() *MostFrequent {
+	return &MostFrequent{positiveOnly: true}
+}
+
+// Reset records the clauses to count.
+func (h *MostFrequent) Reset(variables *VariableTable, clauses [][]Lit) {
+	h.clauses = clauses
+	h.frequency = make([]int, variables.Len())
+}
+
+// Select returns the unassigned variable appearing most often in unsatisfied clauses.
+func (h *MostFrequent) Select(assignment Assignment) (Var, bool) {
+	for i := range h.frequency {
+		h.frequency[i] = 0
+	}
+
+clauses:
+	for _, c := range h.clauses {
+		for _, l := range c {
+			if assignment.Value(l) == LTrue {
+				continue clauses
+			}
+		}
+
+		for _, l := range c {
+			if assignment.Value(l) != LUndef {
+				continue
+			}
+			switch {
+			case h.positiveOnly && l.Negated():
+			case h.positiveOnly:
+				h.frequency[l.Var()]++
+			case l.Negated():
+				h.frequency[l.Var()] += 10
+			default:
+				// Bias towards positive literals.
+				h.frequency[l.Var()] += 14
+			}
+		}
+	}
+
+	best, maxFrequency := Var(0), 0
+	for v, frequency := range h.frequency {
+		if frequency > maxFrequency {
+			best, maxFrequency = Var(v), frequency
+		}
+	}
+	if maxFrequency > 0 {
+		return best, true
+	}
+	return firstUnassigned(assignment)
+}
+
+// Learned does nothing.
+func (h *MostFrequent) Learned([]Lit) {}
+
+// Unassigned does nothing.
+func (h *MostFrequent) Unassigned(Var) {}
+
+// MinimumRemainingValues branches within the most constrained group of variables.
+// Each group should describe mutually exclusive choices, such as the possible values of a sudoku cell. The heuristic
+// picks the undecided group with the fewest candidates left, and branches on its first candidate.
+type MinimumRemainingValues struct {
+	names  [][]string
+	groups [][]Var
+}
+
+// NewMinimumRemainingValues creates a MinimumRemainingValues heuristic over groups of named variables.
+func NewMinimumRemainingValues(groups [][]string) *MinimumRemainingValues {
+	return &MinimumRemainingValues{names: groups}
+}
+
+// Reset resolves the group names to variables. Names that aren't in the formula are ignored.
+func (h *MinimumRemainingValues) Reset(variables *VariableTable, clauses [][]Lit) {
+	h.groups = make([][]Var, 0, len(h.names))
+	for _, names := range h.names {
+		group := make([]Var, 0, len(names))
+		for _, name := range names {
+			if v, ok := variables.Lookup(name); ok {
+				group = append(group, v)
+			}
+		}
+		h.groups = append(h.groups, group)
+	}
+}
+
+// Select returns the first candidate of the undecided group with the fewest candidates.
+// Variables outside the groups are selected once every group is decided.
+func (h *MinimumRemainingValues) Select(assignment Assignment) (Var, bool) {
+	var best Var
+	minRemaining := 0
+groups:
+	for _, group := range h.groups {
+		remaining := 0
+		var first Var
+		for _, v := range group {
+			switch assignment[v] {
+			case LTrue:
+				continue groups
+			case LUndef:
+				if remaining == 0 {
+					first = v
+				}
+				remaining++
+			}
+		}
+
+		if remaining > 0 && (minRemaining == 0 || remaining < minRemaining) {
+			best = first
+			minRemaining = remaining
+		}
+	}
+	if minRemaining > 0 {
+		return best, true
+	}
+	return firstUnassigned(assignment)
+}
+
+// Learned does nothing.
+func (h *MinimumRemainingValues) Learned([]Lit) {}
+
+// Unassigned does nothing.
+func (h *MinimumRemainingValues) Unassigned(Var) {}
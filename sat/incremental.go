@@ -0,0 +1,67 @@
+package sat
+
+// Solver is an incremental solver. Clauses can be added between searches, and each search can make temporary
+// assumptions. Everything learned by a search is kept for the next, so solving many similar problems is much faster
+// than solving each from scratch.
+type Solver struct {
+	s *solver
+}
+
+// NewSolver creates an incremental solver with no clauses.
+// The options apply to every search. Budgets apply to each search, while the stats accumulate.
+func NewSolver(options Options) *Solver {
+	s := newSolver(EmptyConjunctiveFormula())
+	s.options = options
+	return &Solver{s}
+}
+
+// AddClause adds a clause, which must hold in every later search.
+func (s *Solver) AddClause(c DisjunctiveClause) {
+	s.AddFormula(c.ToFormula())
+}
+
+// AddFormula adds every clause and every cardinality, linear and XOR constraint of a formula, which must hold in every
+// later search.
+func (s *Solver) AddFormula(formula ConjunctiveFormula) {
+	s.s.cancelUntil(0)
+	compiled := formula.Compile(s.s.variables)
+	s.s.grow()
+	for _, lits := range compiled.Clauses() {
+		s.s.addClause(lits)
+	}
+	for _, c := range compiled.Composites() {
+		s.s.addComposite(c)
+	}
+	for _, c := range compiled.Cardinalities() {
+		s.s.addCardinality(c)
+	}
+	for _, c := range compiled.Linears() {
+		s.s.addLinear(c)
+	}
+	for _, c := range compiled.Xors() {
+		s.s.addXor(c)
+	}
+}
+
+// Solve searches for a satisfying assignment in which every assumption is true.
+// Assumptions only hold for this search. They must be plain literals.
+// If the result is unsatisfiable because of the assumptions, its failed assumptions are a subset of the assumptions
+// that can't all hold together. If it is unsatisfiable with no failed assumptions, no later search can succeed.
+func (s *Solver) Solve(assumptions ...Literal) Result {
+	s.s.cancelUntil(0)
+	s.s.assumptions = make([]Lit, 0, len(assumptions))
+	for _, assumption := range assumptions {
+		s.s.assumptions = append(s.s.assumptions, s.s.variables.Lit(assumption))
+	}
+	s.s.grow()
+
+	result := Result{Status: s.s.solve()}
+	result.Stats = s.s.stats
+	switch result.Status {
+	case Satisfiable:
+		result.Model = s.s.model()
+	case Unsatisfiable:
+		result.FailedAssumptions = s.s.variables.literals(s.s.failed)
+	}
+	return result
+}
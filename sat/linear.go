@@ -0,0 +1,289 @@
+package sat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WeightedLiteral is a literal counted with a weight in a linear constraint.
+type WeightedLiteral struct {
+	literal Literal
+	weight  int
+}
+
+// Weighted creates a literal counted with the given weight.
+func Weighted(weight int, literal Literal) WeightedLiteral {
+	return WeightedLiteral{literal, weight}
+}
+
+// Literal returns the counted literal.
+func (w WeightedLiteral) Literal() Literal {
+	return w.literal
+}
+
+// Weight returns the amount the literal adds to the sum when it is true.
+func (w WeightedLiteral) Weight() int {
+	return w.weight
+}
+
+func (w WeightedLiteral) String() string {
+	return fmt.Sprintf("%d %s", w.weight, w.literal)
+}
+
+// LinearConstraint asserts that the weighted sum of its true literals is within bounds. This is a pseudo-Boolean
+// constraint: min <= w1 x1 + ... + wn xn <= max. Weights may be negative, and literals must be plain.
+//
+// The solver propagates linear constraints directly: once the literals already true leave less room under the bound
+// than some literal's weight, that literal is implied false. ToCNF expands them into clauses where pure CNF is needed.
+type LinearConstraint struct {
+	terms    []WeightedLiteral
+	min, max int
+}
+
+// SumAtMost creates a constraint that the weighted sum of the true literals is at most k.
+func SumAtMost(k int, terms ...WeightedLiteral) LinearConstraint {
+	lowest, _ := sumRange(terms)
+	return LinearConstraint{terms, lowest, k}
+}
+
+// SumAtLeast creates a constraint that the weighted sum of the true literals is at least k.
+func SumAtLeast(k int, terms ...WeightedLiteral) LinearConstraint {
+	_, highest := sumRange(terms)
+	return LinearConstraint{terms, k, highest}
+}
+
+// SumEquals creates a constraint that the weighted sum of the true literals is exactly k.
+func SumEquals(k int, terms ...WeightedLiteral) LinearConstraint {
+	return LinearConstraint{terms, k, k}
+}
+
+// Terms returns the weighted literals summed by this constraint.
+func (c LinearConstraint) Terms() []WeightedLiteral {
+	return c.terms
+}
+
+// Bounds returns the least and greatest sums allowed.
+func (c LinearConstraint) Bounds() (min, max int) {
+	return c.min, c.max
+}
+
+// Evaluate evaluates this constraint, returning a simplified constraint or a bool.
+func (c LinearConstraint) Evaluate(state map[string]bool) interface{} {
+	remaining := make([]WeightedLiteral, 0, len(c.terms))
+	sum := 0
+	for _, term := range c.terms {
+		switch value := term.literal.Evaluate(state).(type) {
+		case bool:
+			if value {
+				sum += term.weight
+			}
+		case Literal:
+			remaining = append(remaining, WeightedLiteral{value, term.weight})
+		default:
+			panic("Unexpected type!")
+		}
+	}
+
+	min, max := c.min-sum, c.max-sum
+	lowest, highest := sumRange(remaining)
+	if max < lowest || min > highest {
+		return false
+	}
+	if min <= lowest && max >= highest {
+		return true
+	}
+	if min < lowest {
+		min = lowest
+	}
+	if max > highest {
+		max = highest
+	}
+	return LinearConstraint{remaining, min, max}
+}
+
+// ToFormula returns a formula containing this constraint.
+func (c LinearConstraint) ToFormula() ConjunctiveFormula {
+	return ConjunctiveFormula{linears: []LinearConstraint{c}}
+}
+
+func (c LinearConstraint) String() string {
+	strs := make([]string, 0, len(c.terms))
+	for _, term := range c.terms {
+		strs = append(strs, term.String())
+	}
+	sum := strings.Join(strs, " + ")
+
+	lowest, highest := sumRange(c.terms)
+	switch {
+	case c.min == c.max:
+		return fmt.Sprintf("%s = %d", sum, c.min)
+	case c.min <= lowest:
+		return fmt.Sprintf("%s <= %d", sum, c.max)
+	case c.max >= highest:
+		return fmt.Sprintf("%s >= %d", sum, c.min)
+	default:
+		return fmt.Sprintf("%d <= %s <= %d", c.min, sum, c.max)
+	}
+}
+
+// upperBounds returns this constraint as two upper bounds on sums of positive weights, without repeated variables:
+// one for the maximum, and one for the minimum, as a maximum on the negated literals.
+func (c LinearConstraint) upperBounds() (terms [2][]WeightedLiteral, bounds [2]int) {
+	negated := make([]WeightedLiteral, 0, len(c.terms))
+	for _, term := range c.terms {
+		negated = append(negated, WeightedLiteral{term.literal, -term.weight})
+	}
+	terms[0], bounds[0] = normalizeTerms(c.terms, c.max)
+	terms[1], bounds[1] = normalizeTerms(negated, -c.min)
+	return terms, bounds
+}
+
+// normalizeTerms rewrites a bound on a weighted sum into an equivalent bound with positive weights and no variable
+// counted twice, sorted by descending weight. A negative term is the constant weight, less the weight of the negated
+// literal.
+func normalizeTerms(terms []WeightedLiteral, bound int) ([]WeightedLiteral, int) {
+	weights := make(map[string]int) // The weight of each variable's positive literal.
+	names := make([]string, 0, len(terms))
+	for _, term := range terms {
+		name := term.literal.Name()
+		if _, ok := weights[name]; !ok {
+			names = append(names, name)
+		}
+		if _, negative := term.literal.(NegativeLiteral); negative {
+			weights[name] -= term.weight
+			bound -= term.weight
+		} else {
+			weights[name] += term.weight
+		}
+	}
+
+	normalized := make([]WeightedLiteral, 0, len(names))
+	for _, name := range names {
+		switch weight := weights[name]; {
+		case weight > 0:
+			normalized = append(normalized, WeightedLiteral{NewLiteral(name), weight})
+		case weight < 0:
+			normalized = append(normalized, WeightedLiteral{NewLiteral(name).Negate(), -weight})
+			bound -= weight
+		}
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].weight > normalized[j].weight
+	})
+	return normalized, bound
+}
+
+// sumRange returns the least and greatest weighted sums the terms can take.
+func sumRange(terms []WeightedLiteral) (lowest, highest int) {
+	for _, term := range terms {
+		if term.weight < 0 {
+			lowest += term.weight
+		} else {
+			highest += term.weight
+		}
+	}
+	return lowest, highest
+}
+
+// linear is a linear constraint as seen by the solver: the weights of its true literals sum to at most max.
+// Weights are positive and sorted in descending order, and every constraint is kept in this form, like cardinalities.
+// The solver sums the weights of the true literals as they are assigned. Once a literal's weight is more than the
+// room left under the maximum, it is implied false.
+type linear struct {
+	lits    []Lit
+	weights []int
+	max     int
+	sum     int // The total weight of the lits currently true.
+}
+
+// linearWatch is a linear constraint summing a literal, with the literal's weight in it.
+type linearWatch struct {
+	c      *linear
+	weight int
+}
+
+// addLinear adds an original linear constraint to the solver. The solver must be at the root level.
+func (s *solver) addLinear(c LinearConstraint) {
+	terms, bounds := c.upperBounds()
+	for i := range terms {
+		lits := make([]Lit, 0, len(terms[i]))
+		weights := make([]int, 0, len(terms[i]))
+		for _, term := range terms[i] {
+			lits = append(lits, s.variables.Lit(term.literal))
+			weights = append(weights, term.weight)
+		}
+		s.addSumAtMost(lits, weights, bounds[i])
+	}
+}
+
+// addSumAtMost adds a constraint that the weights of the true literals sum to at most max.
+// Weights must be positive and in descending order. Equal weights are counted as a cardinality constraint instead.
+func (s *solver) addSumAtMost(lits []Lit, weights []int, max int) {
+	if max < 0 {
+		s.ok = false
+		return
+	}
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= max {
+		return
+	}
+	if weights[0] == weights[len(weights)-1] {
+		s.addAtMost(lits, max/weights[0])
+		return
+	}
+
+	c := &linear{lits: lits, weights: weights, max: max}
+	for i, l := range lits {
+		if s.assigns.Value(l) == LTrue {
+			c.sum += weights[i]
+		}
+		s.linearWatches[l] = append(s.linearWatches[l], linearWatch{c, weights[i]})
+	}
+	s.linears = append(s.linears, c)
+}
+
+// propagateLinear implies false every unassigned literal of a linear constraint whose weight would take the sum past
+// the maximum. Returns a conflicting clause if the sum is already past it.
+func (s *solver) propagateLinear(c *linear) *clause {
+	slack := c.max - c.sum
+	if slack < 0 {
+		return s.explainLinear(c, nil, c.max)
+	}
+
+	for i, l := range c.lits {
+		if c.weights[i] <= slack {
+			// The rest are no heavier.
+			break
+		}
+		if s.assigns.Value(l) == LUndef {
+			implied := l.Not()
+			s.enqueue(implied, s.explainLinear(c, &implied, c.max-c.weights[i]))
+		}
+	}
+	return nil
+}
+
+// explainLinear returns a reason clause for a linear constraint implying a literal, or for it conflicting if implied
+// is nil: the implied literal, followed by the negations of enough true literals to sum past the given limit.
+// The heaviest literals are used first, to keep the clause short.
+func (s *solver) explainLinear(c *linear, implied *Lit, limit int) *clause {
+	lits := make([]Lit, 0)
+	if implied != nil {
+		lits = append(lits, *implied)
+	}
+	sum := 0
+	for i, l := range c.lits {
+		if sum > limit {
+			break
+		}
+		if s.assigns.Value(l) == LTrue {
+			lits = append(lits, l.Not())
+			sum += c.weights[i]
+		}
+	}
+	return &clause{lits: lits}
+}
@@ -7,7 +7,7 @@ import (
 
 // Expression is a boolean expression.
 type Expression interface {
-	// Evaluate evaluates this expression to a bool if possible, otherwise a simplified Expression of the same type.
+	// Evaluate evaluates this expression to a bool if possible, otherwise a simplified Expression.
 	Evaluate(map[string]bool) interface{}
 }
 
@@ -130,3 +130,7 @@ func (cl CompositeLiteral) Names() []string {
 	}
 	return names
 }
+
+func (cl CompositeLiteral) String() string {
+	return cl.Name()
+}
@@ -0,0 +1,60 @@
+package sat
+
+// SolverObserver is notified of events during a search.
+// Events are reported synchronously, so observers should be quick.
+type SolverObserver interface {
+	// OnDecision is called when the solver branches on a literal, before it is assigned.
+	OnDecision(literal Literal, state SearchState)
+	// OnPropagate is called when a literal is implied by the current assignment.
+	OnPropagate(literal Literal, state SearchState)
+	// OnConflict is called with a clause that is falsified by the current assignment.
+	OnConflict(conflict []Literal, state SearchState)
+	// OnBacktrack is called after the solver undoes every assignment above the given decision level.
+	OnBacktrack(level int, state SearchState)
+	// OnSolution is called with each satisfying assignment found.
+	OnSolution(model map[string]bool)
+}
+
+// NoopObserver ignores every event.
+// Embed it to implement only some of the SolverObserver methods.
+type NoopObserver struct{}
+
+// OnDecision does nothing.
+func (NoopObserver) OnDecision(Literal, SearchState) {}
+
+// OnPropagate does nothing.
+func (NoopObserver) OnPropagate(Literal, SearchState) {}
+
+// OnConflict does nothing.
+func (NoopObserver) OnConflict([]Literal, SearchState) {}
+
+// OnBacktrack does nothing.
+func (NoopObserver) OnBacktrack(int, SearchState) {}
+
+// OnSolution does nothing.
+func (NoopObserver) OnSolution(map[string]bool) {}
+
+// SearchState is a read-only view of a search in progress.
+// It is only valid during the observer call it is passed to.
+type SearchState struct {
+	s *solver
+}
+
+// DecisionLevel returns the number of decisions in the current assignment.
+func (state SearchState) DecisionLevel() int {
+	return state.s.decisionLevel()
+}
+
+// Value returns the value of the named variable, and whether it is assigned.
+func (state SearchState) Value(name string) (value bool, ok bool) {
+	v, ok := state.s.variables.Lookup(name)
+	if !ok || state.s.assigns[v] == LUndef {
+		return false, false
+	}
+	return state.s.assigns[v] == LTrue, true
+}
+
+// Assignment returns the current assignment, keyed by name.
+func (state SearchState) Assignment() map[string]bool {
+	return state.s.model()
+}
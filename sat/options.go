@@ -0,0 +1,61 @@
+package sat
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Status is the outcome of a search.
+type Status int
+
+const (
+	// Unknown means the search was stopped before it found an answer.
+	Unknown Status = iota
+	// Satisfiable means the formula has a satisfying assignment.
+	Satisfiable
+	// Unsatisfiable means the formula has no satisfying assignment.
+	Unsatisfiable
+)
+
+func (s Status) String() string {
+	switch s {
+	case Unknown:
+		return "UNKNOWN"
+	case Satisfiable:
+		return "SAT"
+	case Unsatisfiable:
+		return "UNSAT"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Options configure a search.
+// The zero value searches until an answer is found.
+type Options struct {
+	Context      context.Context // Cancelling the context stops the search. Nil never cancels.
+	Deadline     time.Time       // The search stops at this time, if set.
+	MaxDecisions int             // The search stops after this many decisions, if positive.
+	MaxConflicts int             // The search stops after this many conflicts, if positive.
+	Observer     SolverObserver  // Notified of search events, if set.
+
+	Heuristic BranchingHeuristic // Chooses branching variables. Nil uses VSIDS.
+	Phase     Phase              // Chooses the value tried first for a branching variable.
+	Restarts  RestartPolicy      // Schedules restarts. Nil uses Luby restarts.
+
+	ClauseDatabase ClauseDatabase // Configures how learned clauses are kept.
+	Proof          *ProofWriter   // Records a DRAT proof of unsatisfiability, if set.
+	Preprocess     bool           // Whether to simplify the formula before searching, with Preprocess.
+	Profile        bool           // Whether to time propagation and composite literals, which slows the search.
+}
+
+// Result is the result of a search.
+type Result struct {
+	Status Status
+	Model  map[string]bool // A satisfying assignment, if the formula is satisfiable.
+	Stats  Stats
+
+	// The assumptions responsible for an unsatisfiable result, when solving with assumptions.
+	FailedAssumptions []Literal
+}
@@ -0,0 +1,147 @@
+package sat
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// sharedClauseBuffer is the number of shared clauses a worker can have waiting before more are dropped.
+const sharedClauseBuffer = 1024
+
+// PortfolioOptions configure a parallel search.
+type PortfolioOptions struct {
+	Context context.Context // Cancelling the context stops every worker. Nil never cancels.
+	// The configuration of each worker. Their contexts are replaced by the portfolio's, and proofs aren't supported.
+	// Heuristics and restart policies hold state, so each worker needs its own. Nil uses a default portfolio with a
+	// worker per CPU.
+	Workers []Options
+	// Learned clauses with at most this many literals are shared with the other workers. Zero shares nothing.
+	ShareLength int
+}
+
+// DefaultPortfolio returns n worker configurations that differ in their heuristics, seeds, phases and restarts.
+func DefaultPortfolio(n int) []Options {
+	workers := make([]Options, 0, n)
+	for i := 0; i < n; i++ {
+		seed := int64(i)
+		var options Options
+		switch i % 4 {
+		case 0:
+			options = Options{Heuristic: NewRandomizedVSIDS(0, seed), Phase: PhaseSaving, Restarts: NewLubyRestarts(0)}
+		case 1:
+			options = Options{Heuristic: NewRandomizedVSIDS(0.9, seed), Phase: PhaseFalse, Restarts: NewGeometricRestarts(0, 0)}
+		case 2:
+			options = Options{Heuristic: NewRandomizedVSIDS(0.99, seed), Phase: PhaseTrue, Restarts: NewRandomizedRestarts(0, seed)}
+		default:
+			options = Options{Heuristic: NewShortestClause(), Phase: PhaseSaving, Restarts: NewRandomizedRestarts(0, seed)}
+		}
+		workers = append(workers, options)
+	}
+	return workers
+}
+
+// SolvePortfolio searches for a satisfying assignment with several workers at once, each on its own goroutine with
+// its own configuration. Returns the first definite answer, and cancels the other workers.
+// The result's stats are those of the worker that answered. If every worker stops without an answer, the result is
+// Unknown.
+func SolvePortfolio(formula ConjunctiveFormula, state map[string]bool, options PortfolioOptions) Result {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Workers share clauses by variable ID, so every worker must number variables the same way. Adding the state as
+	// unit clauses, in a fixed order, makes sure it does.
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// The units go in a copy of the clauses, so they can't be appended into the caller's backing array.
+	clauses := append(make([]DisjunctiveClause, 0, len(formula.clauses)+len(names)), formula.clauses...)
+	for _, name := range names {
+		var literal Literal = NewLiteral(name)
+		if !state[name] {
+			literal = literal.Negate()
+		}
+		clauses = append(clauses, NewDisjunctiveClause(literal))
+	}
+	formula.clauses = clauses
+
+	if options.Workers == nil {
+		options.Workers = DefaultPortfolio(runtime.NumCPU())
+	}
+	workers := make([]*solver, len(options.Workers))
+	inboxes := make([]chan []Lit, len(options.Workers))
+	for i, workerOptions := range options.Workers {
+		workerOptions.Context = ctx
+		workerOptions.Proof = nil
+		workers[i] = newSolver(formula)
+		workers[i].options = workerOptions
+		if options.ShareLength > 0 {
+			inboxes[i] = make(chan []Lit, sharedClauseBuffer)
+			workers[i].imports = inboxes[i]
+		}
+	}
+	if options.ShareLength > 0 {
+		for i, worker := range workers {
+			worker.exports = shareWith(i, inboxes, options.ShareLength)
+		}
+	}
+
+	results := make(chan Result, len(workers))
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(s *solver) {
+			defer wg.Done()
+			result := Result{Status: s.solve()}
+			result.Stats = s.stats
+			if result.Status == Satisfiable {
+				result.Model = s.model()
+			}
+			results <- result
+		}(worker)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	answer := Result{Status: Unknown}
+	for result := range results {
+		if result.Status != Unknown {
+			cancel()
+			answer = result
+			break
+		}
+		answer.Stats = result.Stats
+	}
+	// Wait for the cancelled workers, so none outlive the call.
+	for range results {
+	}
+	return answer
+}
+
+// shareWith returns a function sending short learned clauses from one worker to every other worker.
+// Sending never blocks: clauses are dropped for workers that are behind on their imports.
+func shareWith(from int, inboxes []chan []Lit, maxLength int) func([]Lit) {
+	return func(lits []Lit) {
+		if len(lits) > maxLength {
+			return
+		}
+		for to, inbox := range inboxes {
+			if to == from {
+				continue
+			}
+			select {
+			case inbox <- lits:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,500 @@
+package sat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Limits on bounded variable elimination, to keep preprocessing fast.
+const (
+	maxEliminationOccurrences = 16 // Variables occurring more often than this in both polarities aren't eliminated.
+	maxResolventLength        = 20 // Variables with longer resolvents aren't eliminated.
+)
+
+// PreprocessStats describes how much preprocessing shrank a formula.
+type PreprocessStats struct {
+	Variables           int // Variables in the original formula.
+	Clauses             int // Clauses in the original formula.
+	RemainingVariables  int // Variables left in the simplified formula.
+	RemainingClauses    int // Clauses left in the simplified formula.
+	Duplicates          int // Duplicate and tautological clauses removed.
+	Subsumed            int // Clauses removed because another clause subsumed them.
+	Strengthened        int // Literals removed by self-subsuming resolution.
+	EliminatedVariables int // Variables removed by bounded variable elimination.
+
+	Probing ProbeStats // What probing deduced. Its equivalences count the variables substituted away.
+
+	Time time.Duration
+}
+
+func (s PreprocessStats) String() string {
+	lines := []string{
+		fmt.Sprintf("variables:            %d -> %d", s.Variables, s.RemainingVariables),
+		fmt.Sprintf("clauses:              %d -> %d", s.Clauses, s.RemainingClauses),
+		fmt.Sprintf("duplicates:           %d", s.Duplicates),
+		fmt.Sprintf("subsumed:             %d", s.Subsumed),
+		fmt.Sprintf("strengthened:         %d", s.Strengthened),
+		fmt.Sprintf("eliminated variables: %d", s.EliminatedVariables),
+		s.Probing.String(),
+		fmt.Sprintf("preprocessing time:   %s", s.Time),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Preprocessed is a formula simplified by Preprocess, which can reconstruct models of the original formula.
+type Preprocessed struct {
+	formula   ConjunctiveFormula
+	variables *VariableTable
+	stack     []eliminatedClause
+	stats     PreprocessStats
+}
+
+// eliminatedClause is a clause removed by variable elimination or substitution, along with the literal of the removed
+// variable.
+type eliminatedClause struct {
+	pivot Lit
+	lits  []Lit
+}
+
+// Formula returns the simplified formula. It is satisfiable exactly when the original formula is.
+func (p Preprocessed) Formula() ConjunctiveFormula {
+	return p.formula
+}
+
+// Stats describes how much the formula shrank.
+func (p Preprocessed) Stats() PreprocessStats {
+	return p.stats
+}
+
+// Reconstruct extends a model of the simplified formula to a model of the original formula.
+// Variables that were removed are given values that satisfy the clauses they were removed with.
+func (p Preprocessed) Reconstruct(model map[string]bool) map[string]bool {
+	reconstructed := make(map[string]bool, p.variables.Len())
+	for name, value := range model {
+		reconstructed[name] = value
+	}
+	for v := 0; v < p.variables.Len(); v++ {
+		if _, ok := reconstructed[p.variables.Name(Var(v))]; !ok {
+			reconstructed[p.variables.Name(Var(v))] = false
+		}
+	}
+
+	// Clauses are satisfied in the reverse order of their elimination, since later eliminations only depend on
+	// variables that were still present.
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		eliminated := p.stack[i]
+		satisfied := false
+		for _, l := range eliminated.lits {
+			if reconstructed[p.variables.Name(l.Var())] != l.Negated() {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			reconstructed[p.variables.Name(eliminated.pivot.Var())] = !eliminated.pivot.Negated()
+		}
+	}
+	return reconstructed
+}
+
+// Preprocess simplifies a formula before solving.
+// First the formula is probed, as by Probe: failed literals are added as units, hyper-binary resolvents as binary
+// clauses, and variables equivalent to another literal are substituted by it. Then duplicate clauses and clauses
+// subsumed by others are removed, literals are removed by self-subsuming resolution, and variables are eliminated by
+// resolution where that doesn't add clauses.
+// The frozen variables are never eliminated or substituted, so their values in a model of the simplified formula can
+// be relied on. Variables mentioned by composite literals or by cardinality, linear or XOR constraints are always
+// frozen, and their clauses and constraints are kept as-is.
+func Preprocess(formula ConjunctiveFormula, frozen ...string) Preprocessed {
+	start := time.Now()
+	compiled := formula.Compile(NewVariableTable())
+	p := newPreprocessor(compiled, frozen)
+
+	p.probeAll(compiled)
+	p.subsumeAll()
+	p.eliminateAll()
+
+	result := Preprocessed{variables: compiled.Variables(), stack: p.stack, stats: p.stats}
+	clauses := make([]DisjunctiveClause, 0)
+	present := make([]bool, compiled.Variables().Len())
+	for _, c := range p.clauses {
+		if c.removed {
+			continue
+		}
+		clauses = append(clauses, NewDisjunctiveClause(compiled.Variables().literals(c.lits)...))
+		for _, l := range c.lits {
+			present[l.Var()] = true
+		}
+	}
+	for _, v := range p.constrained {
+		present[v] = true
+	}
+	for _, isPresent := range present {
+		if isPresent {
+			result.stats.RemainingVariables++
+		}
+	}
+	result.stats.RemainingClauses = len(clauses) + len(compiled.Composites())
+	result.formula = ConjunctiveFormula{
+		append(clauses, compiled.Composites()...),
+		compiled.Cardinalities(),
+		compiled.Linears(),
+		compiled.Xors(),
+	}
+	result.stats.Time = time.Since(start)
+	return result
+}
+
+// preprocessClause is a clause being preprocessed. Its literals are sorted.
+type preprocessClause struct {
+	lits    []Lit
+	removed bool
+	queued  bool // Whether the clause is waiting to be checked for subsuming others.
+}
+
+type preprocessor struct {
+	clauses     []*preprocessClause
+	occurs      [][]*preprocessClause // The clauses containing each literal. Removed clauses are dropped lazily.
+	frozen      []bool
+	eliminated  []bool
+	constrained []Var // Variables mentioned by composite literals or by cardinality, linear or XOR constraints.
+	queue       []*preprocessClause
+	marks       []bool // Scratch space, indexed by literal.
+	stack       []eliminatedClause
+	stats       PreprocessStats
+}
+
+func newPreprocessor(compiled CompiledFormula, frozen []string) *preprocessor {
+	variables := compiled.Variables()
+	p := &preprocessor{
+		occurs:     make([][]*preprocessClause, 2*variables.Len()),
+		frozen:     make([]bool, variables.Len()),
+		eliminated: make([]bool, variables.Len()),
+		marks:      make([]bool, 2*variables.Len()),
+	}
+	p.stats.Variables = variables.Len()
+	p.stats.Clauses = len(compiled.Clauses()) + len(compiled.Composites())
+
+	for _, name := range frozen {
+		if v, ok := variables.Lookup(name); ok {
+			p.frozen[v] = true
+		}
+	}
+	for _, c := range compiled.Composites() {
+		for _, literal := range c.literals {
+			for _, name := range literal.Names() {
+				p.freezeConstrained(variables, name)
+			}
+		}
+	}
+	for _, c := range compiled.Cardinalities() {
+		for _, literal := range c.literals {
+			p.freezeConstrained(variables, literal.Name())
+		}
+	}
+	for _, c := range compiled.Linears() {
+		for _, term := range c.terms {
+			p.freezeConstrained(variables, term.literal.Name())
+		}
+	}
+	for _, c := range compiled.Xors() {
+		for _, literal := range c.literals {
+			p.freezeConstrained(variables, literal.Name())
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, lits := range compiled.Clauses() {
+		lits, ok := normalize(lits)
+		key := fmt.Sprint(lits)
+		if !ok || seen[key] {
+			p.stats.Duplicates++
+			continue
+		}
+		seen[key] = true
+		p.add(lits)
+	}
+	return p
+}
+
+// freezeConstrained freezes a variable mentioned by a constraint the preprocessor can't simplify.
+func (p *preprocessor) freezeConstrained(variables *VariableTable, name string) {
+	v, _ := variables.Lookup(name)
+	if !p.frozen[v] {
+		p.constrained = append(p.constrained, v)
+	}
+	p.frozen[v] = true
+}
+
+// normalize sorts a clause and removes repeated literals.
+// Returns false if the clause is a tautology.
+func normalize(lits []Lit) ([]Lit, bool) {
+	lits = append([]Lit(nil), lits...)
+	sort.Slice(lits, func(i, j int) bool { return lits[i] < lits[j] })
+
+	kept := lits[:0]
+	for i, l := range lits {
+		if i > 0 && l == lits[i-1].Not() {
+			return nil, false
+		}
+		if i > 0 && l == lits[i-1] {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return kept, true
+}
+
+// add adds a clause with sorted literals, and queues it to check for subsumption.
+func (p *preprocessor) add(lits []Lit) {
+	c := &preprocessClause{lits: lits}
+	p.clauses = append(p.clauses, c)
+	for _, l := range lits {
+		p.occurs[l] = append(p.occurs[l], c)
+	}
+	p.enqueue(c)
+}
+
+func (p *preprocessor) enqueue(c *preprocessClause) {
+	if !c.queued {
+		c.queued = true
+		p.queue = append(p.queue, c)
+	}
+}
+
+// occurrences returns the clauses containing a literal, dropping removed clauses from its list.
+func (p *preprocessor) occurrences(l Lit) []*preprocessClause {
+	kept := p.occurs[l][:0]
+	for _, c := range p.occurs[l] {
+		if !c.removed {
+			kept = append(kept, c)
+		}
+	}
+	p.occurs[l] = kept
+	return kept
+}
+
+// subsumeAll checks queued clauses for subsuming or strengthening others, until nothing changes.
+// Shorter clauses are checked first, since they subsume the most.
+func (p *preprocessor) subsumeAll() {
+	for len(p.queue) > 0 {
+		queue := p.queue
+		p.queue = nil
+		sort.SliceStable(queue, func(i, j int) bool { return len(queue[i].lits) < len(queue[j].lits) })
+		for _, c := range queue {
+			c.queued = false
+			if !c.removed {
+				p.subsume(c)
+			}
+		}
+	}
+}
+
+// subsume removes the clauses that contain every literal of c, and strengthens the clauses that contain every
+// literal of c but one, which they contain negated: resolving the two removes that literal.
+func (p *preprocessor) subsume(c *preprocessClause) {
+	if len(c.lits) == 0 {
+		return
+	}
+
+	// Every candidate contains the rarest variable of c, in one polarity or the other.
+	best := c.lits[0]
+	for _, l := range c.lits[1:] {
+		if len(p.occurrences(l))+len(p.occurrences(l.Not())) < len(p.occurrences(best))+len(p.occurrences(best.Not())) {
+			best = l
+		}
+	}
+	candidates := append(append([]*preprocessClause(nil), p.occurrences(best)...), p.occurrences(best.Not())...)
+
+	for _, l := range c.lits {
+		p.marks[l] = true
+	}
+	for _, d := range candidates {
+		if d == c || d.removed || len(d.lits) < len(c.lits) {
+			continue
+		}
+
+		matched, negated, negatedCount := 0, Lit(0), 0
+		for _, l := range d.lits {
+			if p.marks[l] {
+				matched++
+			} else if p.marks[l.Not()] {
+				negated = l
+				negatedCount++
+			}
+		}
+
+		switch {
+		case matched == len(c.lits):
+			d.removed = true
+			p.stats.Subsumed++
+		case matched == len(c.lits)-1 && negatedCount == 1:
+			p.strengthen(d, negated)
+		}
+	}
+	for _, l := range c.lits {
+		p.marks[l] = false
+	}
+}
+
+// strengthen removes a literal from a clause.
+func (p *preprocessor) strengthen(c *preprocessClause, l Lit) {
+	kept := c.lits[:0]
+	for _, other := range c.lits {
+		if other != l {
+			kept = append(kept, other)
+		}
+	}
+	c.lits = kept
+
+	occurs := p.occurs[l]
+	for i, other := range occurs {
+		if other == c {
+			p.occurs[l] = append(occurs[:i], occurs[i+1:]...)
+			break
+		}
+	}
+
+	p.stats.Strengthened++
+	p.enqueue(c)
+}
+
+// eliminateAll tries to eliminate every variable, starting with the rarest.
+func (p *preprocessor) eliminateAll() {
+	vars := make([]Var, 0, len(p.frozen))
+	for v := range p.frozen {
+		vars = append(vars, Var(v))
+	}
+	count := func(v Var) int {
+		return len(p.occurrences(NewLit(v, false))) + len(p.occurrences(NewLit(v, true)))
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return count(vars[i]) < count(vars[j]) })
+
+	for _, v := range vars {
+		if !p.frozen[v] && !p.eliminated[v] && p.eliminate(v) {
+			p.subsumeAll()
+		}
+	}
+}
+
+// eliminate replaces the clauses containing a variable with their resolvents on it, if there are no more resolvents
+// than clauses. Returns whether the variable was eliminated.
+func (p *preprocessor) eliminate(v Var) bool {
+	positive, negative := p.occurrences(NewLit(v, false)), p.occurrences(NewLit(v, true))
+	if len(positive) > maxEliminationOccurrences && len(negative) > maxEliminationOccurrences {
+		return false
+	}
+
+	resolvents := make([][]Lit, 0)
+	for _, a := range positive {
+		for _, b := range negative {
+			resolvent, ok := p.resolve(a.lits, b.lits, v)
+			if !ok {
+				continue
+			}
+			if len(resolvent) > maxResolventLength || len(resolvents) == len(positive)+len(negative) {
+				return false
+			}
+			resolvents = append(resolvents, resolvent)
+		}
+	}
+
+	for _, occurrences := range [][]*preprocessClause{positive, negative} {
+		for _, c := range occurrences {
+			for _, l := range c.lits {
+				if l.Var() == v {
+					p.stack = append(p.stack, eliminatedClause{pivot: l, lits: c.lits})
+				}
+			}
+			c.removed = true
+		}
+	}
+	p.eliminated[v] = true
+	p.stats.EliminatedVariables++
+
+	for _, resolvent := range resolvents {
+		p.add(resolvent)
+	}
+	return true
+}
+
+// resolve returns the resolvent of two sorted clauses on a variable, or false if it is a tautology.
+func (p *preprocessor) resolve(a, b []Lit, v Var) ([]Lit, bool) {
+	resolvent := make([]Lit, 0, len(a)+len(b)-2)
+	for _, l := range a {
+		if l.Var() != v {
+			resolvent = append(resolvent, l)
+		}
+	}
+	for _, l := range b {
+		if l.Var() != v {
+			resolvent = append(resolvent, l)
+		}
+	}
+	return normalize(resolvent)
+}
+
+// probeAll probes the formula, then adds what probing learned and substitutes equivalent variables.
+func (p *preprocessor) probeAll(compiled CompiledFormula) {
+	start := time.Now()
+	defer func() {
+		p.stats.Probing.Time = time.Since(start)
+	}()
+
+	s := newCompiledSolver(compiled)
+	binaries, ok := s.probe(&p.stats.Probing)
+	var representatives []Lit
+	if ok {
+		representatives, ok = s.equivalences(func(v Var) bool { return p.frozen[v] })
+	}
+	if !ok {
+		p.add([]Lit{})
+		return
+	}
+
+	for _, l := range s.trail {
+		p.add([]Lit{l})
+	}
+	for _, lits := range binaries {
+		lits, _ := normalize(lits)
+		p.add(lits)
+	}
+	for v, r := range representatives {
+		if r.Var() != Var(v) && !p.frozen[v] {
+			p.substitute(Var(v), r)
+		}
+	}
+}
+
+// substitute replaces a variable with an equivalent literal over another variable, in every clause containing it.
+// Clauses that become tautologies are removed. The equivalence goes on the reconstruction stack as two clauses.
+func (p *preprocessor) substitute(v Var, r Lit) {
+	positive := NewLit(v, false)
+	for _, l := range []Lit{positive, positive.Not()} {
+		for _, c := range append([]*preprocessClause(nil), p.occurrences(l)...) {
+			lits := make([]Lit, 0, len(c.lits))
+			for _, other := range c.lits {
+				switch other {
+				case positive:
+					other = r
+				case positive.Not():
+					other = r.Not()
+				}
+				lits = append(lits, other)
+			}
+			c.removed = true
+			if lits, ok := normalize(lits); ok {
+				p.add(lits)
+			}
+		}
+	}
+
+	p.stack = append(p.stack,
+		eliminatedClause{pivot: positive, lits: []Lit{positive, r.Not()}},
+		eliminatedClause{pivot: positive.Not(), lits: []Lit{positive.Not(), r}},
+	)
+	p.eliminated[v] = true
+	p.stats.Probing.Equivalences++
+}
@@ -0,0 +1,81 @@
+package sat
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomNames returns n variable names.
+func randomNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("x%d", i)
+	}
+	return names
+}
+
+// randomLiteral returns one of the named variables, negated half the time.
+func randomLiteral(r *rand.Rand, names []string) Literal {
+	var literal Literal = NewLiteral(names[r.Intn(len(names))])
+	if r.Intn(2) == 0 {
+		literal = literal.Negate()
+	}
+	return literal
+}
+
+// randomClauses returns a formula of up to count clauses of one to three literals over the named variables.
+func randomClauses(r *rand.Rand, names []string, count int) ConjunctiveFormula {
+	clauses := make([]DisjunctiveClause, 0, count)
+	for i := 0; i < count; i++ {
+		literals := make([]Literal, 1+r.Intn(3))
+		for j := range literals {
+			literals[j] = randomLiteral(r, names)
+		}
+		clauses = append(clauses, NewDisjunctiveClause(literals...))
+	}
+	return NewConjunctiveFormula(clauses)
+}
+
+func TestPreprocessReconstruct(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		names := randomNames(3 + r.Intn(8))
+		formula := randomClauses(r, names, r.Intn(4*len(names)))
+		for j := r.Intn(3); j > 0; j-- {
+			// Equivalent literals, for probing to substitute.
+			a, b := randomLiteral(r, names), randomLiteral(r, names)
+			formula = formula.And(NewConjunctiveFormula([]DisjunctiveClause{
+				NewDisjunctiveClause(a.Negate(), b),
+				NewDisjunctiveClause(a, b.Negate()),
+			}))
+		}
+		frozen := make([]string, 0)
+		for _, name := range names {
+			if r.Intn(4) == 0 {
+				frozen = append(frozen, name)
+			}
+		}
+
+		preprocessed := Preprocess(formula, frozen...)
+		model, ok := Solve(preprocessed.Formula(), map[string]bool{})
+		if _, want := Solve(formula, map[string]bool{}); ok != want {
+			t.Errorf("%s: preprocessed satisfiable = %v, want %v\n%s", formula, ok, want, preprocessed.Formula())
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		reconstructed := preprocessed.Reconstruct(model)
+		if formula.Evaluate(reconstructed) != true {
+			t.Errorf("%s: reconstructed model %v doesn't satisfy the formula\n%s", formula, reconstructed,
+				preprocessed.Formula())
+		}
+		for _, name := range frozen {
+			if value, ok := model[name]; ok && reconstructed[name] != value {
+				t.Errorf("%s: frozen variable %s changed from %v", formula, name, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package sat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProbeStats describes what probing deduced, and how much work it took.
+// The counts are a rough measure of how hard a formula is to reason about without search.
+type ProbeStats struct {
+	Rounds         int // Passes over the variables. Probing stops after a pass that finds no failed literal.
+	Probes         int // Literals assumed and propagated.
+	FailedLiterals int // Literals whose propagation conflicted, so whose negation was learned.
+	HyperBinary    int // Binary clauses learned by hyper-binary resolution.
+	Equivalences   int // Variables found equivalent to a literal over another variable.
+
+	Time time.Duration
+}
+
+func (s ProbeStats) String() string {
+	lines := []string{
+		fmt.Sprintf("probing rounds:       %d", s.Rounds),
+		fmt.Sprintf("probes:               %d", s.Probes),
+		fmt.Sprintf("failed literals:      %d", s.FailedLiterals),
+		fmt.Sprintf("hyper-binary:         %d", s.HyperBinary),
+		fmt.Sprintf("equivalences:         %d", s.Equivalences),
+		fmt.Sprintf("probing time:         %s", s.Time),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ProbeResult is what probing deduced about a formula.
+type ProbeResult struct {
+	// Unsatisfiable if probing refuted the formula, Satisfiable if it assigned every variable, and Unknown otherwise.
+	Status Status
+	Fixed  map[string]bool // The value every model gives a variable, for each variable probing assigned.
+	Stats  ProbeStats
+
+	// For each variable equivalent to another, a literal over the variable representing its class.
+	Equivalent map[string]Literal
+}
+
+// Probe deduces what it can about a formula without search.
+// Each unassigned variable is assumed true, then false, and propagated. A literal whose propagation conflicts has
+// failed, so its negation is learned. Rounds repeat until a round finds no failed literal.
+// A literal implied through a longer clause also gives a binary clause from the assumption to it, by hyper-binary
+// resolution. Literals that imply each other through binary clauses, and at-most-one constraints, are equivalent:
+// they form a strongly connected component of the binary implication graph.
+func Probe(formula ConjunctiveFormula) ProbeResult {
+	start := time.Now()
+	s := newSolver(formula)
+	result := ProbeResult{Fixed: make(map[string]bool), Equivalent: make(map[string]Literal)}
+
+	_, ok := s.probe(&result.Stats)
+	var representatives []Lit
+	if ok {
+		representatives, ok = s.equivalences(func(Var) bool { return false })
+	}
+	if !ok {
+		result.Status, result.Fixed, result.Equivalent = Unsatisfiable, nil, nil
+		result.Stats.Time = time.Since(start)
+		return result
+	}
+
+	for _, l := range s.trail {
+		result.Fixed[s.variables.Name(l.Var())] = !l.Negated()
+	}
+	for v, r := range representatives {
+		if r.Var() != Var(v) {
+			result.Equivalent[s.variables.Name(Var(v))] = s.variables.Literal(r)
+			result.Stats.Equivalences++
+		}
+	}
+	if len(s.trail) == len(s.assigns) {
+		result.Status = Satisfiable
+	}
+	result.Stats.Time = time.Since(start)
+	return result
+}
+
+// probe probes both literals of every unassigned variable at the root level, in rounds until a round finds no failed
+// literal. Failed literals are learned as units, and hyper-binary resolvents as binary clauses.
+// Returns the binary clauses learned, or false if the formula is unsatisfiable.
+func (s *solver) probe(stats *ProbeStats) ([][]Lit, bool) {
+	if !s.ok || !s.propagateAll() {
+		s.ok = false
+		return nil, false
+	}
+
+	binaries := make([][]Lit, 0)
+	learned := make(map[[2]Lit]bool)
+	for failed := true; failed; {
+		failed = false
+		stats.Rounds++
+		for v := range s.assigns {
+			for _, l := range []Lit{NewLit(Var(v), false), NewLit(Var(v), true)} {
+				if s.assigns[v] != LUndef {
+					break
+				}
+
+				stats.Probes++
+				s.trailLimits = append(s.trailLimits, len(s.trail))
+				s.enqueue(l, nil)
+				if s.propagate() != nil {
+					s.cancelUntil(0)
+					stats.FailedLiterals++
+					failed = true
+					s.enqueue(l.Not(), nil)
+					if s.propagate() != nil {
+						s.ok = false
+						return nil, false
+					}
+					continue
+				}
+
+				// Every implied literal follows from l alone, so a binary clause can stand in for a longer reason.
+				resolvents := make([][]Lit, 0)
+				for _, x := range s.trail[s.trailLimits[0]+1:] {
+					reason := s.reasons[x.Var()]
+					if reason == nil || len(reason.lits) <= 2 {
+						continue
+					}
+					key := [2]Lit{l.Not(), x}
+					if key[1] < key[0] {
+						key[0], key[1] = key[1], key[0]
+					}
+					if !learned[key] {
+						learned[key] = true
+						resolvents = append(resolvents, []Lit{l.Not(), x})
+					}
+				}
+				s.cancelUntil(0)
+
+				for _, lits := range resolvents {
+					if s.addClause(lits) != nil {
+						binaries = append(binaries, lits)
+						stats.HyperBinary++
+					}
+				}
+			}
+		}
+	}
+	return binaries, true
+}
+
+// equivalences finds the strongly connected components of the binary implication graph, whose literals all imply each
+// other, at the root level. Each component is represented by one of its literals, preferring those over variables for
+// which prefer returns true, then the lowest variable. The component of a literal's negation is represented by the
+// negation of its representative.
+// Returns the representative of each variable's positive literal, or false if a literal is equivalent to its negation.
+func (s *solver) equivalences(prefer func(Var) bool) ([]Lit, bool) {
+	edges := make([][]Lit, 2*len(s.assigns))
+	unassigned := func(lits ...Lit) bool {
+		for _, l := range lits {
+			if s.assigns.Value(l) != LUndef {
+				return false
+			}
+		}
+		return true
+	}
+	for _, clauses := range [][]*clause{s.clauses, s.learnts} {
+		for _, c := range clauses {
+			if len(c.lits) == 2 && unassigned(c.lits...) {
+				a, b := c.lits[0], c.lits[1]
+				edges[a.Not()] = append(edges[a.Not()], b)
+				edges[b.Not()] = append(edges[b.Not()], a)
+			}
+		}
+	}
+	for _, c := range s.cardinalities {
+		if c.max != 1 {
+			continue
+		}
+		for _, a := range c.lits {
+			for _, b := range c.lits {
+				if a != b && unassigned(a, b) {
+					edges[a] = append(edges[a], b.Not())
+				}
+			}
+		}
+	}
+
+	// Tarjan's algorithm finds each component after every component reachable from it.
+	index := make([]int, len(edges)) // The order each literal was visited in, from 1. Zero if not yet visited.
+	low := make([]int, len(edges))
+	onStack := make([]bool, len(edges))
+	resolved := make([]bool, len(edges))
+	representatives := make([]Lit, len(edges))
+	stack := make([]Lit, 0)
+	visited := 0
+	ok := true
+
+	var visit func(l Lit)
+	visit = func(l Lit) {
+		visited++
+		index[l], low[l] = visited, visited
+		stack = append(stack, l)
+		onStack[l] = true
+		for _, m := range edges[l] {
+			if index[m] == 0 {
+				visit(m)
+				if low[m] < low[l] {
+					low[l] = low[m]
+				}
+			} else if onStack[m] && index[m] < low[l] {
+				low[l] = index[m]
+			}
+		}
+		if low[l] != index[l] {
+			return
+		}
+
+		i := len(stack) - 1
+		for stack[i] != l {
+			i--
+		}
+		component := stack[i:]
+		stack = stack[:i]
+
+		representative := component[0]
+		if resolved[representative.Not()] {
+			representative = representatives[representative.Not()].Not()
+		} else {
+			for _, m := range component[1:] {
+				if preferred, current := prefer(m.Var()), prefer(representative.Var()); preferred != current {
+					if preferred {
+						representative = m
+					}
+				} else if m.Var() < representative.Var() {
+					representative = m
+				}
+			}
+		}
+		for _, m := range component {
+			onStack[m] = false
+			resolved[m] = true
+			representatives[m] = representative
+		}
+		for _, m := range component {
+			if resolved[m.Not()] && representatives[m.Not()] == representative {
+				ok = false
+			}
+		}
+	}
+
+	for l := range edges {
+		if index[l] == 0 {
+			visit(Lit(l))
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+
+	result := make([]Lit, len(s.assigns))
+	for v := range result {
+		result[v] = representatives[NewLit(Var(v), false)]
+	}
+	return result, true
+}
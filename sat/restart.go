@@ -0,0 +1,140 @@
+package sat
+
+import (
+	"math"
+	"math/rand"
+)
+
+// RestartPolicy schedules restarts, which abandon the current search tree and resume from the root level.
+// Learned clauses, heuristic scores and saved phases survive a restart, so the search resumes with what it learned
+// but with a fresh branching order.
+// Policies hold schedule state, so an instance shouldn't be shared between searches.
+type RestartPolicy interface {
+	// Reset starts the schedule from the beginning. It is called before every search.
+	Reset()
+	// Next returns the number of conflicts to allow before the next restart.
+	// Zero or less never restarts again.
+	Next() int
+}
+
+// noRestarts never restarts.
+type noRestarts struct{}
+
+// NoRestarts returns a policy that never restarts, committing to the search tree for the whole search.
+func NoRestarts() RestartPolicy {
+	return noRestarts{}
+}
+
+func (noRestarts) Reset() {}
+
+func (noRestarts) Next() int {
+	return 0
+}
+
+// LubyRestarts restarts after a number of conflicts following the Luby sequence 1, 1, 2, 1, 1, 2, 4, 1, ...,
+// multiplied by a unit. The sequence is within a constant factor of optimal when the run time distribution is unknown.
+type LubyRestarts struct {
+	unit  int
+	index int
+}
+
+// NewLubyRestarts creates a Luby restart policy with the given unit, in conflicts.
+// Units of zero or less use the default of 100.
+func NewLubyRestarts(unit int) *LubyRestarts {
+	if unit <= 0 {
+		unit = 100
+	}
+	return &LubyRestarts{unit: unit}
+}
+
+// Reset starts the schedule from the beginning.
+func (p *LubyRestarts) Reset() {
+	p.index = 0
+}
+
+// Next returns the next interval of the sequence.
+func (p *LubyRestarts) Next() int {
+	p.index++
+	return p.unit * luby(p.index)
+}
+
+// luby returns the ith term of the Luby sequence, counting from 1.
+func luby(i int) int {
+	// The sequence is made of blocks ending at 2^k - 1, each repeating the previous blocks then ending in 2^(k-1).
+	for k := uint(1); ; k++ {
+		if i == 1<<k-1 {
+			return 1 << (k - 1)
+		}
+		if i >= 1<<(k-1) && i < 1<<k-1 {
+			return luby(i - 1<<(k-1) + 1)
+		}
+	}
+}
+
+// GeometricRestarts restarts after a number of conflicts that grows by a constant factor after every restart.
+type GeometricRestarts struct {
+	first    int
+	factor   float64
+	interval float64
+}
+
+// NewGeometricRestarts creates a geometric restart policy, with the given first interval in conflicts and growth
+// factor. Intervals of zero or less use the default of 100, and factors of 1 or less use the default of 1.5.
+func NewGeometricRestarts(first int, factor float64) *GeometricRestarts {
+	if first <= 0 {
+		first = 100
+	}
+	if factor <= 1 {
+		factor = 1.5
+	}
+	return &GeometricRestarts{first: first, factor: factor}
+}
+
+// Reset starts the schedule from the beginning.
+func (p *GeometricRestarts) Reset() {
+	p.interval = 0
+}
+
+// Next returns the next interval, which is the previous one multiplied by the growth factor.
+func (p *GeometricRestarts) Next() int {
+	if p.interval == 0 {
+		p.interval = float64(p.first)
+	} else {
+		p.interval *= p.factor
+	}
+	if p.interval > math.MaxInt32 {
+		p.interval = math.MaxInt32
+	}
+	return int(p.interval)
+}
+
+// RandomizedRestarts restarts after a random number of conflicts, drawn from an exponential distribution.
+// Random intervals avoid restarting in lockstep with any structure in the problem.
+type RandomizedRestarts struct {
+	mean   float64
+	seed   int64
+	random *rand.Rand
+}
+
+// NewRandomizedRestarts creates a randomized restart policy with the given mean interval in conflicts.
+// Means of zero or less use the default of 100. The same seed always gives the same schedule.
+func NewRandomizedRestarts(mean int, seed int64) *RandomizedRestarts {
+	if mean <= 0 {
+		mean = 100
+	}
+	return &RandomizedRestarts{mean: float64(mean), seed: seed, random: rand.New(rand.NewSource(seed))}
+}
+
+// Reset starts the schedule from the beginning.
+func (p *RandomizedRestarts) Reset() {
+	p.random.Seed(p.seed)
+}
+
+// Next returns a random interval of at least one conflict.
+func (p *RandomizedRestarts) Next() int {
+	interval := p.random.ExpFloat64() * p.mean
+	if interval > math.MaxInt32 {
+		interval = math.MaxInt32
+	}
+	return int(interval) + 1
+}
@@ -1,173 +1,102 @@
 package sat
 
-import (
-	"fmt"
-	"time"
-)
-
-const showIterationTimes = false
+import "fmt"
 
 // Solve attempts to solve the given formula, given the initial state.
-func Solve(formula ConjunctiveFormula, state map[string]bool, display func(map[string]bool) string) (map[string]bool, bool) {
-	var start time.Time
-	if showIterationTimes {
-		start = time.Now()
-	}
-
-	fmt.Println(display(state))
-
-	// Evaluate and unit propagate as much as possible.
-	ok := true
-	for ok {
-		// fmt.Println(len(state))
-		switch expr := formula.Evaluate(state).(type) {
-		case bool:
-			if expr {
-				return state, true
-			}
-			return nil, false
-		case ConjunctiveFormula:
-			state, ok = propagate(expr, state)
-			formula = expr
-		default:
-			panic("Unexpected type!")
-		}
-	}
-
-	testState := make(map[string]bool)
-	for k, v := range state {
-		testState[k] = v
+// Returns a satisfying assignment for every variable in the formula, or false if there is none.
+func Solve(formula ConjunctiveFormula, state map[string]bool) (map[string]bool, bool) {
+	s := newSolver(formula)
+	if !s.assume(state) || s.solve() != Satisfiable {
+		return nil, false
 	}
+	return s.model(), true
+}
 
-	litName := selectLiteral(formula)
-	fmt.Println(litName)
-	if showIterationTimes {
-		fmt.Println(time.Since(start))
+// SolveWithOptions attempts to solve the given formula, given the initial state.
+// Unlike Solve, the search can be limited by its options, in which case the result's status is Unknown.
+func SolveWithOptions(formula ConjunctiveFormula, state map[string]bool, options Options) Result {
+	if options.Proof != nil {
+		formula = formula.ToCNF(SequentialCounterEncoding)
 	}
 
-	// False first is better for visualizing. This causes earlier assumptions to stay on the board longer.
-	// True first might be better for speed, since setting a value to true has a lot of downstream propagation.
-
-	testState[litName] = true
-	// fmt.Println(true)
-	if solutionState, ok := Solve(formula, testState, display); ok {
-		return solutionState, true
+	var preprocessed Preprocessed
+	if options.Preprocess {
+		frozen := make([]string, 0, len(state))
+		for name := range state {
+			frozen = append(frozen, name)
+		}
+		preprocessed = Preprocess(formula, frozen...)
+		formula = preprocessed.Formula()
+		if options.Proof != nil {
+			options.Proof.fail(fmt.Errorf("cannot prove a preprocessed formula"))
+		}
 	}
 
-	testState[litName] = false
-	// fmt.Println(false)
-	if solutionState, ok := Solve(formula, testState, display); ok {
-		return solutionState, true
+	s := newSolver(formula)
+	s.options = options
+	s.stats.Preprocessing = preprocessed.stats
+	if !s.assume(state) {
+		return Result{Status: Unsatisfiable, Stats: s.stats}
 	}
 
-	return state, false
-}
-
-// propagate uses unit propagation to determine additional variable values
-func propagate(formula ConjunctiveFormula, state map[string]bool) (newState map[string]bool, changed bool) {
-	newState = make(map[string]bool)
-	for k, v := range state {
-		newState[k] = v
-	}
-
-	for _, clause := range formula.clauses {
-		if len(clause.literals) == 1 {
-			switch literal := clause.literals[0].(type) {
-			case PositiveLiteral:
-				changed = true
-				newState[literal.Name()] = true
-			case NegativeLiteral:
-				changed = true
-				newState[literal.Name()] = false
-			case CompositeLiteral:
-				continue
-			default:
-				panic("Unexpected type!")
-			}
+	result := Result{Status: s.solve()}
+	result.Stats = s.stats
+	if result.Status == Satisfiable {
+		result.Model = s.model()
+		if options.Preprocess {
+			result.Model = preprocessed.Reconstruct(result.Model)
 		}
 	}
-
-	return newState, changed
+	return result
 }
 
-func selectLiteral(formula ConjunctiveFormula) string {
-	// TODO: Pick literal better.
-	// return formula.clauses[0].literals[0].Name()
-	return fromShortestClause(formula)
-	// return mostFrequentLiteral(formula)
-	// return mostFrequentPositiveLiteral(formula)
+// SolveAll returns up to limit satisfying assignments of the given formula.
+// Solutions are distinct over the named variables, or over every variable if none are named. Each solution found is
+// excluded from the rest of the search by a blocking clause over those variables.
+// A limit of zero or less finds every solution.
+func SolveAll(formula ConjunctiveFormula, limit int, names ...string) []map[string]bool {
+	solutions := make([]map[string]bool, 0)
+	enumerate(formula, limit, names, func(solution map[string]bool) {
+		solutions = append(solutions, solution)
+	})
+	return solutions
 }
 
-func fromShortestClause(formula ConjunctiveFormula) string {
-	best := formula.clauses[0]
-	// minLength := len(best.literals)
-	minLength := 0
-	for _, literal := range best.literals {
-		minLength += len(literal.Names())
-	}
-
-	for _, clause := range formula.clauses[1:] {
-		// length := len(clause.literals)
-		length := 0
-		for _, literal := range clause.literals {
-			length += len(literal.Names())
-		}
-		if length < minLength {
-			best = clause
-			minLength = length
-		}
-	}
-
-	return best.literals[0].Names()[0]
+// CountSolutions counts the satisfying assignments of the given formula, stopping once limit is reached.
+// Solutions are counted as in SolveAll.
+func CountSolutions(formula ConjunctiveFormula, limit int, names ...string) (count int) {
+	enumerate(formula, limit, names, func(map[string]bool) {
+		count++
+	})
+	return count
 }
 
-func mostFrequentLiteral(formula ConjunctiveFormula) string {
-	frequency := make(map[string]int)
-	for _, clause := range formula.clauses {
-		for _, literal := range clause.literals {
-			for _, name := range literal.Names() {
-				frequency[name] = frequency[name] + 10
-				// Bias towards positive literals.
-				if _, ok := literal.(NegativeLiteral); !ok {
-					frequency[name] = frequency[name] + 4
-				}
-			}
-		}
-	}
+// enumerate calls found with up to limit solutions of the formula that are distinct over the named variables.
+func enumerate(formula ConjunctiveFormula, limit int, names []string, found func(map[string]bool)) {
+	s := newSolver(formula)
+	vars := projection(s, names)
 
-	var name string
-	maxFrequency := -1
-	for k, v := range frequency {
-		if v > maxFrequency {
-			name = k
-			maxFrequency = v
+	for count := 0; limit <= 0 || count < limit; count++ {
+		if s.solve() != Satisfiable {
+			return
 		}
+		found(s.model())
+		s.block(vars)
 	}
-
-	return name
 }
 
-func mostFrequentPositiveLiteral(formula ConjunctiveFormula) string {
-	frequency := make(map[string]int)
-	for _, clause := range formula.clauses {
-		for _, literal := range clause.literals {
-			if _, ok := literal.(NegativeLiteral); ok {
-				continue
-			}
-			for _, name := range literal.Names() {
-				frequency[name] = frequency[name] + 1
-			}
+// projection returns the named variables, or every variable if none are named.
+func projection(s *solver, names []string) []Var {
+	vars := make([]Var, 0, len(names))
+	for _, name := range names {
+		if v, ok := s.variables.Lookup(name); ok {
+			vars = append(vars, v)
 		}
 	}
-
-	var name string
-	maxFrequency := -1
-	for k, v := range frequency {
-		if v > maxFrequency {
-			name = k
-			maxFrequency = v
+	if len(names) == 0 {
+		for v := 0; v < s.variables.Len(); v++ {
+			vars = append(vars, Var(v))
 		}
 	}
-
-	return name
+	return vars
 }
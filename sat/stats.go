@@ -0,0 +1,49 @@
+package sat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Stats describes the work done by a search.
+type Stats struct {
+	Decisions      int // Branches taken.
+	Propagations   int // Literals implied by unit propagation.
+	Conflicts      int // Falsified clauses found.
+	MaxDepth       int // The deepest decision level reached.
+	LearnedClauses int // Clauses learned from conflicts.
+	DeletedClauses int // Learned clauses removed by reductions of the clause database.
+	Restarts       int // Times the search was restarted from the root level.
+	PeakClauses    int // The most clauses held at once, including learned and composite clauses.
+
+	Time            time.Duration // Total time spent searching.
+	PropagationTime time.Duration // Time spent propagating plain clauses, if profiled with Options.Profile.
+	CompositeTime   time.Duration // Time spent reducing composite literals, if profiled with Options.Profile.
+
+	Preprocessing PreprocessStats // How much preprocessing shrank the formula, if it was preprocessed.
+}
+
+func (s Stats) String() string {
+	lines := []string{
+		fmt.Sprintf("decisions:        %d", s.Decisions),
+		fmt.Sprintf("propagations:     %d", s.Propagations),
+		fmt.Sprintf("conflicts:        %d", s.Conflicts),
+		fmt.Sprintf("max depth:        %d", s.MaxDepth),
+		fmt.Sprintf("learned clauses:  %d", s.LearnedClauses),
+		fmt.Sprintf("deleted clauses:  %d", s.DeletedClauses),
+		fmt.Sprintf("restarts:         %d", s.Restarts),
+		fmt.Sprintf("peak clauses:     %d", s.PeakClauses),
+		fmt.Sprintf("time:             %s", s.Time),
+	}
+	if s.PropagationTime > 0 || s.CompositeTime > 0 {
+		lines = append(lines,
+			fmt.Sprintf("propagation time: %s", s.PropagationTime),
+			fmt.Sprintf("composite time:   %s", s.CompositeTime),
+		)
+	}
+	if s.Preprocessing.Variables > 0 {
+		lines = append(lines, s.Preprocessing.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -30,18 +30,7 @@ func Multiply(sets ...Literals) []Literals {
 	return currentProduct
 }
 
-// ExactlyOneTrue returns clauses specifying that exactly one of the given literals is true.
+// ExactlyOneTrue returns a formula specifying that exactly one of the given literals is true.
 func ExactlyOneTrue(literals []Literal) ConjunctiveFormula {
-	clauses := make([]DisjunctiveClause, 0)
-	// At least one literal is true.
-	clauses = append(clauses, NewDisjunctiveClause(literals...))
-
-	for i, litA := range literals {
-		for _, litB := range literals[i+1:] {
-			// No two literals are true.
-			clauses = append(clauses, NewDisjunctiveClause(litA.Negate(), litB.Negate()))
-		}
-	}
-
-	return NewConjunctiveFormula(clauses)
+	return Exactly(1, literals...).ToFormula()
 }
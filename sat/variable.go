@@ -0,0 +1,242 @@
+package sat
+
+// Var is a variable, identified by a dense integer ID.
+type Var int32
+
+// Lit is a packed literal: its variable shifted left by one, with the low bit set if the literal is negated.
+type Lit uint32
+
+// NewLit creates a literal of the given variable.
+func NewLit(v Var, negated bool) Lit {
+	l := Lit(v) << 1
+	if negated {
+		l |= 1
+	}
+	return l
+}
+
+// Var returns the variable in this literal.
+func (l Lit) Var() Var {
+	return Var(l >> 1)
+}
+
+// Negated returns whether this literal is the negation of its variable.
+func (l Lit) Negated() bool {
+	return l&1 == 1
+}
+
+// Not negates this literal.
+func (l Lit) Not() Lit {
+	return l ^ 1
+}
+
+// LBool is a lifted boolean: true, false, or undefined.
+type LBool int8
+
+// Lifted boolean values.
+// These are chosen so that negating a value is the same as negating the number.
+const (
+	LFalse LBool = -1
+	LUndef LBool = 0
+	LTrue  LBool = 1
+)
+
+// LiftBool converts a bool to an LBool.
+func LiftBool(b bool) LBool {
+	if b {
+		return LTrue
+	}
+	return LFalse
+}
+
+// Assignment assigns values to variables. It is indexed by Var.
+type Assignment []LBool
+
+// NewAssignment creates an assignment for n variables, with every variable unassigned.
+func NewAssignment(n int) Assignment {
+	return make(Assignment, n)
+}
+
+// Value returns the value of a literal under this assignment.
+func (a Assignment) Value(l Lit) LBool {
+	value := a[l.Var()]
+	if l.Negated() {
+		return -value
+	}
+	return value
+}
+
+// State returns the assigned variables of this assignment, keyed by name.
+func (a Assignment) State(variables *VariableTable) map[string]bool {
+	state := make(map[string]bool)
+	for v, value := range a {
+		if value != LUndef {
+			state[variables.Name(Var(v))] = value == LTrue
+		}
+	}
+	return state
+}
+
+// VariableTable interns variable names to dense IDs.
+type VariableTable struct {
+	names []string
+	ids   map[string]Var
+}
+
+// NewVariableTable creates an empty variable table.
+func NewVariableTable() *VariableTable {
+	return &VariableTable{ids: make(map[string]Var)}
+}
+
+// Intern returns the ID for the given name, allocating a new one if needed.
+func (t *VariableTable) Intern(name string) Var {
+	if v, ok := t.ids[name]; ok {
+		return v
+	}
+	v := Var(len(t.names))
+	t.names = append(t.names, name)
+	t.ids[name] = v
+	return v
+}
+
+// Lookup returns the ID for the given name, if it has been interned.
+func (t *VariableTable) Lookup(name string) (Var, bool) {
+	v, ok := t.ids[name]
+	return v, ok
+}
+
+// Name returns the name of the given variable.
+func (t *VariableTable) Name(v Var) string {
+	return t.names[v]
+}
+
+// Len returns the number of interned variables.
+func (t *VariableTable) Len() int {
+	return len(t.names)
+}
+
+// Lit interns a plain literal, returning its packed form.
+// Panics if the literal is composite.
+func (t *VariableTable) Lit(literal Literal) Lit {
+	switch l := literal.(type) {
+	case PositiveLiteral:
+		return NewLit(t.Intern(l.Name()), false)
+	case NegativeLiteral:
+		return t.Lit(l.literal).Not()
+	default:
+		panic("Cannot intern a composite literal!")
+	}
+}
+
+// Literal returns the named form of a packed literal.
+func (t *VariableTable) Literal(l Lit) Literal {
+	literal := NewLiteral(t.Name(l.Var()))
+	if l.Negated() {
+		return literal.Negate()
+	}
+	return literal
+}
+
+// literals returns the named form of the given literals.
+func (t *VariableTable) literals(lits []Lit) []Literal {
+	literals := make([]Literal, 0, len(lits))
+	for _, l := range lits {
+		literals = append(literals, t.Literal(l))
+	}
+	return literals
+}
+
+// CompiledFormula is a ConjunctiveFormula whose plain clauses have been interned to packed literals.
+type CompiledFormula struct {
+	variables     *VariableTable
+	clauses       [][]Lit
+	composites    []DisjunctiveClause
+	cardinalities []CardinalityConstraint
+	linears       []LinearConstraint
+	xors          []XorConstraint
+}
+
+// Compile interns the variables of this formula into the given table.
+// Clauses containing composite literals can't be represented as packed literals, so they are kept as-is, although
+// the variables they mention are still interned. So are cardinality, linear and XOR constraints.
+func (f ConjunctiveFormula) Compile(variables *VariableTable) CompiledFormula {
+	compiled := CompiledFormula{variables: variables}
+	for _, c := range f.clauses {
+		if c.hasComposite() {
+			for _, literal := range c.literals {
+				for _, name := range literal.Names() {
+					variables.Intern(name)
+				}
+			}
+			compiled.composites = append(compiled.composites, c)
+			continue
+		}
+
+		lits := make([]Lit, 0, len(c.literals))
+		for _, literal := range c.literals {
+			lits = append(lits, variables.Lit(literal))
+		}
+		compiled.clauses = append(compiled.clauses, lits)
+	}
+
+	for _, c := range f.cardinalities {
+		for _, literal := range c.literals {
+			variables.Lit(literal)
+		}
+		compiled.cardinalities = append(compiled.cardinalities, c)
+	}
+
+	for _, c := range f.linears {
+		for _, term := range c.terms {
+			variables.Lit(term.literal)
+		}
+		compiled.linears = append(compiled.linears, c)
+	}
+
+	for _, c := range f.xors {
+		for _, literal := range c.literals {
+			variables.Lit(literal)
+		}
+		compiled.xors = append(compiled.xors, c)
+	}
+	return compiled
+}
+
+// Variables returns the table the formula was compiled into.
+func (f CompiledFormula) Variables() *VariableTable {
+	return f.variables
+}
+
+// Clauses returns the plain clauses of the formula.
+func (f CompiledFormula) Clauses() [][]Lit {
+	return f.clauses
+}
+
+// Composites returns the clauses of the formula that contain composite literals.
+func (f CompiledFormula) Composites() []DisjunctiveClause {
+	return f.composites
+}
+
+// Cardinalities returns the cardinality constraints of the formula.
+func (f CompiledFormula) Cardinalities() []CardinalityConstraint {
+	return f.cardinalities
+}
+
+// Linears returns the linear constraints of the formula.
+func (f CompiledFormula) Linears() []LinearConstraint {
+	return f.linears
+}
+
+// Xors returns the XOR constraints of the formula.
+func (f CompiledFormula) Xors() []XorConstraint {
+	return f.xors
+}
+
+// Formula returns the named form of this formula.
+func (f CompiledFormula) Formula() ConjunctiveFormula {
+	clauses := make([]DisjunctiveClause, 0, len(f.clauses)+len(f.composites))
+	for _, lits := range f.clauses {
+		clauses = append(clauses, NewDisjunctiveClause(f.variables.literals(lits)...))
+	}
+	return ConjunctiveFormula{append(clauses, f.composites...), f.cardinalities, f.linears, f.xors}
+}
@@ -0,0 +1,218 @@
+package sat
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// XorConstraint asserts that the number of its literals that are true is odd, or even.
+// Literals must be plain. A literal repeated cancels itself out, as does a literal and its negation, less a flip of the
+// parity.
+//
+// The solver propagates XOR constraints together, by Gaussian elimination: sums of constraints can imply values that
+// no single constraint does. Spelling one out takes 2^(n-1) clauses, so ToCNF first cuts long XORs into short ones
+// joined by auxiliary variables.
+type XorConstraint struct {
+	literals []Literal
+	odd      bool
+}
+
+// OddParity creates a constraint that an odd number of the given literals are true.
+func OddParity(literals ...Literal) XorConstraint {
+	return XorConstraint{literals, true}
+}
+
+// EvenParity creates a constraint that an even number of the given literals are true.
+func EvenParity(literals ...Literal) XorConstraint {
+	return XorConstraint{literals, false}
+}
+
+// Literals returns the literals whose parity is constrained.
+func (c XorConstraint) Literals() []Literal {
+	return c.literals
+}
+
+// Odd returns whether an odd number of the literals must be true.
+func (c XorConstraint) Odd() bool {
+	return c.odd
+}
+
+// Evaluate evaluates this constraint, returning a simplified constraint or a bool.
+func (c XorConstraint) Evaluate(state map[string]bool) interface{} {
+	remaining := make([]Literal, 0, len(c.literals))
+	odd := c.odd
+	for _, literal := range c.literals {
+		switch value := literal.Evaluate(state).(type) {
+		case bool:
+			odd = odd != value
+		case Literal:
+			remaining = append(remaining, value)
+		default:
+			panic("Unexpected type!")
+		}
+	}
+
+	if len(remaining) == 0 {
+		return !odd
+	}
+	return XorConstraint{remaining, odd}
+}
+
+// ToFormula returns a formula containing this constraint.
+func (c XorConstraint) ToFormula() ConjunctiveFormula {
+	return ConjunctiveFormula{xors: []XorConstraint{c}}
+}
+
+func (c XorConstraint) String() string {
+	strs := make([]string, 0, len(c.literals))
+	for _, literal := range c.literals {
+		strs = append(strs, fmt.Sprintf("%s", literal))
+	}
+
+	parity := "even"
+	if c.odd {
+		parity = "odd"
+	}
+	return fmt.Sprintf("%s parity of %s", parity, strings.Join(strs, ", "))
+}
+
+// normalize returns the names of the variables whose parity is constrained, each once, and whether an odd number of
+// them must be true.
+func (c XorConstraint) normalize() ([]string, bool) {
+	odd := c.odd
+	counts := make(map[string]int)
+	names := make([]string, 0, len(c.literals))
+	for _, literal := range c.literals {
+		name := literal.Name()
+		if _, ok := counts[name]; !ok {
+			names = append(names, name)
+		}
+		counts[name]++
+		if _, negative := literal.(NegativeLiteral); negative {
+			// ~x = x XOR 1
+			odd = !odd
+		}
+	}
+
+	kept := names[:0]
+	for _, name := range names {
+		if counts[name]%2 == 1 {
+			kept = append(kept, name)
+		}
+	}
+	return kept, odd
+}
+
+// xorRow is an XOR constraint as seen by the solver: its variables sum to odd, modulo 2.
+type xorRow struct {
+	vars []Var
+	odd  bool
+}
+
+// addXor adds an original XOR constraint to the solver. The solver must be at the root level.
+func (s *solver) addXor(c XorConstraint) {
+	names, odd := c.normalize()
+	row := &xorRow{odd: odd}
+	for _, name := range names {
+		v := s.variables.Intern(name)
+		row.vars = append(row.vars, v)
+		if s.xorColumns[v] < 0 {
+			s.xorColumns[v] = len(s.xorVars)
+			s.xorVars = append(s.xorVars, v)
+		}
+	}
+
+	if len(row.vars) == 0 {
+		if odd {
+			s.ok = false
+		}
+		return
+	}
+	s.xors = append(s.xors, row)
+	s.xorPropagated = 0
+}
+
+// eliminate runs Gauss-Jordan elimination over the XOR constraints, pivoting only on unassigned variables, then
+// implies the value of every variable left alone in its row by the rest being assigned.
+// Each row stays a sum of original constraints, so its assigned variables give the reason.
+// Returns a conflicting clause if a row has every variable assigned, but the wrong parity.
+func (s *solver) eliminate() *clause {
+	if len(s.xors) == 0 {
+		return nil
+	}
+
+	words := (len(s.xorVars) + 63) / 64
+	rows := make([][]uint64, len(s.xors))
+	odd := make([]bool, len(s.xors))
+	for i, row := range s.xors {
+		rows[i] = make([]uint64, words)
+		for _, v := range row.vars {
+			column := s.xorColumns[v]
+			rows[i][column/64] |= 1 << uint(column%64)
+		}
+		odd[i] = row.odd
+	}
+
+	pivoted := 0
+	for column, v := range s.xorVars {
+		if s.assigns[v] != LUndef {
+			continue
+		}
+		word, bit := column/64, uint64(1)<<uint(column%64)
+		pivot := -1
+		for i := pivoted; i < len(rows); i++ {
+			if rows[i][word]&bit != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+
+		rows[pivoted], rows[pivot] = rows[pivot], rows[pivoted]
+		odd[pivoted], odd[pivot] = odd[pivot], odd[pivoted]
+		for i := range rows {
+			if i != pivoted && rows[i][word]&bit != 0 {
+				for w := range rows[i] {
+					rows[i][w] ^= rows[pivoted][w]
+				}
+				odd[i] = odd[i] != odd[pivoted]
+			}
+		}
+		pivoted++
+	}
+
+	for i, row := range rows {
+		unassigned := make([]Var, 0, 1)
+		value := odd[i]
+		lits := make([]Lit, 0)
+		for w, word := range row {
+			for ; word != 0; word &= word - 1 {
+				v := s.xorVars[w*64+bits.TrailingZeros64(word)]
+				switch s.assigns[v] {
+				case LUndef:
+					unassigned = append(unassigned, v)
+				case LTrue:
+					value = !value
+					lits = append(lits, NewLit(v, true))
+				default:
+					lits = append(lits, NewLit(v, false))
+				}
+			}
+		}
+
+		switch len(unassigned) {
+		case 0:
+			if value {
+				// Every variable is assigned, but the parity is wrong.
+				return &clause{lits: lits}
+			}
+		case 1:
+			implied := NewLit(unassigned[0], !value)
+			s.enqueue(implied, &clause{lits: append([]Lit{implied}, lits...)})
+		}
+	}
+	return nil
+}
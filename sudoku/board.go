@@ -220,34 +220,30 @@ func (b Board) KnightMoves(coordinate Coordinate) (coordinates Coordinates) {
 	return coordinates
 }
 
-// AllConstraints returns the constraints from the board's rules and its initial values.
+// AllConstraints returns the constraints from the board's rules, its clues and its initial values.
 func (b Board) AllConstraints() (constraints []Constraint) {
-	for _, rule := range b.rules {
-		constraints = append(constraints, rule.Apply(b)...)
+	for _, tagged := range b.TaggedConstraints() {
+		constraints = append(constraints, tagged.Constraint)
 	}
-
-	for _, clue := range b.clues {
-		constraints = append(constraints, clue.Apply(b)...)
-	}
-
-	for coordinate, value := range b.values {
-		constraints = append(constraints, NewCellValueConstraint(coordinate, value))
-	}
-
 	return constraints
 }
 
 func (b Board) String() string {
+	return b.render(func(coordinate Coordinate) string {
+		if value, ok := b.values[coordinate]; ok {
+			return strconv.Itoa(value)
+		}
+		return " "
+	})
+}
+
+// render draws the board's grid, using cell to draw each cell.
+func (b Board) render(cell func(Coordinate) string) string {
 	rowStrings := make([]string, 0)
 	for row := 1; row <= 9; row++ {
 		rowChars := make([]string, 0)
 		for col := 1; col <= 9; col++ {
-			value, ok := b.values[NewCoordinate(row, col)]
-			if ok {
-				rowChars = append(rowChars, strconv.Itoa(value))
-			} else {
-				rowChars = append(rowChars, " ")
-			}
+			rowChars = append(rowChars, cell(NewCoordinate(row, col)))
 
 			if col%3 == 0 {
 				rowChars = append(rowChars, "|")
@@ -1,5 +1,7 @@
 package sudoku
 
+import "fmt"
+
 // Clue is an initial constraint on the board, other than actual cell values.
 type Clue interface {
 	// Apply applies this clue to a board, returning the corresponding constraints.
@@ -23,6 +25,10 @@ func (t Thermometer) Apply(board Board) []Constraint {
 	}
 }
 
+func (t Thermometer) String() string {
+	return fmt.Sprintf("Thermometer at %s", cellName(t.coordinates[0]))
+}
+
 // Sum specifies cells that sum to a given value.
 type Sum struct {
 	coordinates []Coordinate
@@ -51,6 +57,10 @@ func (s Sum) Apply(board Board) []Constraint {
 	}
 }
 
+func (s Sum) String() string {
+	return fmt.Sprintf("Sum %d at %s", s.sum, cellName(s.coordinates[0]))
+}
+
 // LittleKiller specifies a diagonal that sums to a given value.
 type LittleKiller struct {
 	coordinates []Coordinate
@@ -69,6 +79,10 @@ func (lk LittleKiller) Apply(board Board) []Constraint {
 	}
 }
 
+func (lk LittleKiller) String() string {
+	return fmt.Sprintf("LittleKiller %d at %s", lk.sum, cellName(lk.coordinates[0]))
+}
+
 // KillerCage specifies cells that have unique values, summing to a given value.
 type KillerCage struct {
 	coordinates []Coordinate
@@ -87,3 +101,7 @@ func (kc KillerCage) Apply(board Board) []Constraint {
 		NewUniqueValueConstraint(kc.coordinates...),
 	}
 }
+
+func (kc KillerCage) String() string {
+	return fmt.Sprintf("KillerCage %d at %s", kc.sum, cellName(kc.coordinates[0]))
+}
@@ -0,0 +1,57 @@
+package conversion
+
+import (
+	"sort"
+	"strings"
+
+	sudoku ".."
+	"../../sat"
+)
+
+// Contradiction is a set of a board's constraints that can't all hold together.
+type Contradiction []sudoku.TaggedConstraint
+
+func (c Contradiction) String() string {
+	descriptions := make([]string, 0, len(c))
+	for _, constraint := range c {
+		descriptions = append(descriptions, constraint.String())
+	}
+	return strings.Join(descriptions, " + ") + " are contradictory"
+}
+
+// FindContradiction explains why a board has no solution.
+// Returns a minimal set of the board's constraints that can't all hold together, or false if the board is solvable.
+// Clues are listed first, then givens, then rules, so mistakes in puzzle entry come first.
+func FindContradiction(board sudoku.Board) (Contradiction, bool) {
+	tagged := ToTaggedFormulas(board)
+	groups := make([]sat.ConjunctiveFormula, 0, len(tagged))
+	for _, t := range tagged {
+		groups = append(groups, t.Formula)
+	}
+
+	core, status := sat.UnsatisfiableCore(groups, sat.Options{})
+	if status != sat.Unsatisfiable {
+		return nil, false
+	}
+
+	contradiction := make(Contradiction, 0, len(core))
+	for _, index := range core {
+		contradiction = append(contradiction, tagged[index].Constraint)
+	}
+	sort.SliceStable(contradiction, func(i, j int) bool {
+		return originRank(contradiction[i]) < originRank(contradiction[j])
+	})
+	return contradiction, true
+}
+
+// originRank orders constraints by origin: clues, then givens, then rules.
+func originRank(constraint sudoku.TaggedConstraint) int {
+	switch {
+	case constraint.Clue != nil:
+		return 0
+	case constraint.Given != nil:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -2,7 +2,7 @@ package conversion
 
 import (
 	"fmt"
-	"sort"
+	"math/big"
 
 	sudoku ".."
 	"../../sat"
@@ -20,17 +20,133 @@ func ParseState(state map[string]bool) sudoku.Board {
 	return sudoku.NewStandardBoard(initialValues)
 }
 
+// CellHeuristic returns a minimum remaining values heuristic over the cells of the board.
+func CellHeuristic(board sudoku.Board) sat.BranchingHeuristic {
+	groups := make([][]string, 0)
+	for _, coordinate := range board.AllCoordinates() {
+		names := make([]string, 0)
+		for _, value := range board.AllValues() {
+			names = append(names, litName(coordinate, value))
+		}
+		groups = append(groups, names)
+	}
+	return sat.NewMinimumRemainingValues(groups)
+}
+
+// Solutions returns up to limit distinct solutions to the board.
+// A limit of zero or less returns every solution.
+func Solutions(board sudoku.Board, limit int) []sudoku.Board {
+	boards := make([]sudoku.Board, 0)
+	for _, state := range sat.SolveAll(ToFormula(board), limit, cellNames(board)...) {
+		boards = append(boards, ParseState(state))
+	}
+	return boards
+}
+
+// CountGrids counts the grids satisfying the board's clues exactly, without enumerating them.
+func CountGrids(board sudoku.Board) (*big.Int, error) {
+	return sat.CountModels(ToFormula(board), cellNames(board)...)
+}
+
+// Candidates returns the values each cell can still take in some solution to the board, like complete pencil marks.
+// Cells with a single candidate have the same value in every solution. Returns false if the board has no solution.
+func Candidates(board sudoku.Board) (map[sudoku.Coordinate][]int, bool) {
+	backbone, status := sat.Backbone(ToFormula(board), sat.Options{}, cellNames(board)...)
+	if status != sat.Satisfiable {
+		return nil, false
+	}
+
+	candidates := make(map[sudoku.Coordinate][]int)
+	for _, coordinate := range board.AllCoordinates() {
+		values := make([]int, 0)
+		for _, value := range board.AllValues() {
+			if forcedTrue, forced := backbone[litName(coordinate, value)]; forcedTrue || !forced {
+				values = append(values, value)
+			}
+		}
+		candidates[coordinate] = values
+	}
+	return candidates, true
+}
+
+// ProbeCandidates returns the values each cell can still take after probing the board, like Candidates but found
+// without search, so some may not appear in any solution. How much probing it took measures the strength of reasoning
+// the board needs. Returns false if probing shows the board has no solution.
+func ProbeCandidates(board sudoku.Board) (map[sudoku.Coordinate][]int, sat.ProbeStats, bool) {
+	result := sat.Probe(ToFormula(board))
+	if result.Status == sat.Unsatisfiable {
+		return nil, result.Stats, false
+	}
+
+	candidates := make(map[sudoku.Coordinate][]int)
+	for _, coordinate := range board.AllCoordinates() {
+		values := make([]int, 0)
+		for _, value := range board.AllValues() {
+			if fixedTrue, fixed := result.Fixed[litName(coordinate, value)]; fixedTrue || !fixed {
+				values = append(values, value)
+			}
+		}
+		candidates[coordinate] = values
+	}
+	return candidates, result.Stats, true
+}
+
+// SolutionsCubes returns up to limit distinct solutions to the board, like Solutions, but splits the search into
+// cubes searched in parallel. Cells can be chosen to split on with SplitCells. If none are chosen, the search is split
+// on the first two empty cells.
+func SolutionsCubes(board sudoku.Board, limit int, options sat.CubeOptions) []sudoku.Board {
+	if len(options.Split) == 0 {
+		empty := make([]sudoku.Coordinate, 0, 2)
+		for _, coordinate := range board.AllCoordinates() {
+			if _, ok := board.Value(coordinate); !ok && len(empty) < 2 {
+				empty = append(empty, coordinate)
+			}
+		}
+		options.Split = SplitCells(board, empty...)
+	}
+
+	boards := make([]sudoku.Board, 0)
+	for _, state := range sat.SolveAllCubes(ToFormula(board), limit, cellNames(board), options) {
+		boards = append(boards, ParseState(state))
+	}
+	return boards
+}
+
+// SplitCells returns the variables for every value of the given cells, to split a search on.
+func SplitCells(board sudoku.Board, cells ...sudoku.Coordinate) []string {
+	names := make([]string, 0)
+	for _, coordinate := range cells {
+		for _, value := range board.AllValues() {
+			names = append(names, litName(coordinate, value))
+		}
+	}
+	return names
+}
+
 // ToFormula converts a board to CNF form.
 func ToFormula(board sudoku.Board) (formula sat.ConjunctiveFormula) {
-	constraints := board.AllConstraints()
-
-	for _, constraint := range constraints {
-		formula = formula.And(convert(constraint, board))
+	for _, tagged := range ToTaggedFormulas(board) {
+		formula = formula.And(tagged.Formula)
 	}
 
 	return formula
 }
 
+// TaggedFormula is the CNF form of one of a board's constraints, tagged with the constraint and its origin.
+type TaggedFormula struct {
+	Formula    sat.ConjunctiveFormula
+	Constraint sudoku.TaggedConstraint
+}
+
+// ToTaggedFormulas converts each of a board's constraints to CNF form separately.
+func ToTaggedFormulas(board sudoku.Board) []TaggedFormula {
+	formulas := make([]TaggedFormula, 0)
+	for _, constraint := range board.TaggedConstraints() {
+		formulas = append(formulas, TaggedFormula{convert(constraint.Constraint, board), constraint})
+	}
+	return formulas
+}
+
 func convert(c sudoku.Constraint, board sudoku.Board) sat.ConjunctiveFormula {
 	switch constraint := c.(type) {
 	case sudoku.CellValueConstraint:
@@ -69,14 +185,7 @@ func convert(c sudoku.Constraint, board sudoku.Board) sat.ConjunctiveFormula {
 
 		return formula.And(sat.NewConjunctiveFormula(clauses))
 	case sudoku.ConstantSumConstraint:
-		allValues := board.AllValues()
-		literals := make([]sat.Literal, 0)
-		for _, coordinate := range constraint.Coordinates() {
-			literals = append(literals, toLiterals(coordinate, allValues)...)
-		}
-
-		sumLiteral := sumLiterals(literals, constraint.Sum(), len(constraint.Coordinates()), board.Size())
-		return sat.NewDisjunctiveClause(sumLiteral).ToFormula()
+		return sumValues(constraint.Coordinates(), board.AllValues(), constraint.Sum())
 	default:
 		panic(fmt.Sprintf("Unknown constraint type: %T", c))
 	}
@@ -90,142 +199,31 @@ func constrainToValues(coordinate sudoku.Coordinate, values []int) sat.Conjuncti
 	return sat.ExactlyOneTrue(literals)
 }
 
-// sumLiterals creates a composite literal that is true if there are numSummands cells with values adding to sum.
-// It only considers the given literals, and assumes each summand has a value from [1, maxValue].
-// Repeated summands are allowed.
-func sumLiterals(l []sat.Literal, sum int, numSummands int, maxValue int) sat.CompositeLiteral {
-	return sat.NewCompositeLiteral(l, func(literals []sat.Literal, state map[string]bool) interface{} {
-		currentSum := 0
-		currentSummands := 0
-		undeterminedLiterals := make([]sat.Literal, 0)
-
-		for _, literal := range literals {
-			switch result := literal.Evaluate(state).(type) {
-			case bool:
-				_, isPositive := literal.(sat.PositiveLiteral)
-				_, isNegative := literal.(sat.NegativeLiteral)
-				hasValue := (result && isPositive) || (!result && isNegative)
-
-				if hasValue {
-					_, value := fromName(literal.Name())
-					currentSum += value
-					currentSummands++
-				}
-			case sat.Literal:
-				undeterminedLiterals = append(undeterminedLiterals, result)
-			default:
-				panic("Unexpected result type!")
-			}
-		}
-
-		// We have all the info needed to fully evaluate the sum.
-		if len(undeterminedLiterals) == 0 {
-			return currentSum == sum
-		}
-
-		// We've found the desired number of summands.
-		if currentSummands == numSummands {
-			return currentSum == sum
-		}
-
-		remainingSum := sum - currentSum
-		remainingSummands := numSummands - currentSummands
-
-		// We will always exceed the target sum with the number of summands we have left.
-		// This also works for remainingSummands == 0.
-		if remainingSummands*1 > remainingSum {
-			return false
-		}
-
-		// It's impossible to reach the target sum with the number of summands we have left.
-		if remainingSummands*maxValue < remainingSum {
-			return false
-		}
-
-		// // Eliminate literals with values that are too high.
-		// newUndeterminedLiterals := make([]sat.Literal, 0)
-		// for _, literal := range undeterminedLiterals {
-		// 	_, value := fromName(literal.Name())
-		// 	if value <= remainingSum {
-		// 		newUndeterminedLiterals = append(newUndeterminedLiterals, literal)
-		// 	}
-		// }
-		// undeterminedLiterals = newUndeterminedLiterals
-
-		// There aren't enough possible values to reach the total.
-		if len(undeterminedLiterals) < remainingSummands {
-			return false
-		}
-
-		// More comprehensive checks to see if we can reach the target sum.
-		minValues := minLiteralValues(undeterminedLiterals, remainingSummands)
-		minSum := 0
-		for _, value := range minValues {
-			minSum += value
-		}
-		if minSum > remainingSum {
-			return false
-		}
-
-		maxValues := maxLiteralValues(undeterminedLiterals, remainingSummands)
-		maxSum := 0
-		for _, value := range maxValues {
-			maxSum += value
+// sumValues specifies that the values of the given cells add up to sum, as two weighted sums over their value literals.
+// Each cell has exactly one value, so weighting each value by how far it is above the least value bounds the sum from
+// above, and weighting it by how far it is below the greatest bounds the sum from below. A cell without a value yet
+// counts as its most favourable value either way, so a value is ruled out once the other cells can't make up the rest.
+func sumValues(coordinates []sudoku.Coordinate, values []int, sum int) sat.ConjunctiveFormula {
+	least, greatest := values[0], values[0]
+	for _, value := range values {
+		if value < least {
+			least = value
 		}
-		if maxSum < remainingSum {
-			return false
+		if value > greatest {
+			greatest = value
 		}
+	}
 
-		// If there's only one summand left, look for matching literals.
-		if remainingSummands == 1 {
-			newUndeterminedLiterals := make([]sat.Literal, 0)
-			for _, literal := range undeterminedLiterals {
-				_, value := fromName(literal.Name())
-				if value == remainingSum {
-					newUndeterminedLiterals = append(newUndeterminedLiterals, literal)
-				}
-			}
-
-			undeterminedLiterals = newUndeterminedLiterals
-			switch len(undeterminedLiterals) {
-			case 0:
-				// The last cell can't have the desired value.
-				return false
-			case 1:
-				// There's exactly one possibility left, so we can reduce to a non-composite literal.
-				return undeterminedLiterals[0]
-			}
+	above := make([]sat.WeightedLiteral, 0)
+	below := make([]sat.WeightedLiteral, 0)
+	for _, coordinate := range coordinates {
+		for _, value := range values {
+			literal := toLiteral(coordinate, value)
+			above = append(above, sat.Weighted(value-least, literal))
+			below = append(below, sat.Weighted(greatest-value, literal))
 		}
-
-		// if remainingSummands == 2 {
-		// 	fmt.Println(remainingSum, remainingSummands, undeterminedLiterals)
-		// }
-
-		// if currentSum != 0 {
-		// 	fmt.Println(len(undeterminedLiterals), sum, currentSum, remainingSummands)
-		// 	fmt.Println(undeterminedLiterals)
-		// }
-
-		return sumLiterals(undeterminedLiterals, remainingSum, remainingSummands, maxValue)
-	})
-}
-
-func minLiteralValues(literals []sat.Literal, n int) []int {
-	values := make([]int, 0)
-	for _, literal := range literals {
-		_, value := fromName(literal.Name())
-		values = append(values, value)
 	}
-	sort.Ints(values)
-	return values[:n]
-}
 
-func maxLiteralValues(literals []sat.Literal, n int) []int {
-	values := make([]int, 0)
-	for _, literal := range literals {
-		_, value := fromName(literal.Name())
-		values = append(values, value)
-	}
-	sort.Ints(values)
-	return values[len(values)-n : len(values)]
+	n := len(coordinates)
+	return sat.SumAtMost(sum-n*least, above...).ToFormula().And(sat.SumAtMost(n*greatest-sum, below...).ToFormula())
 }
@@ -3,15 +3,12 @@ package conversion
 import (
 	"fmt"
 	"strconv"
-
-	"regexp"
+	"strings"
 
 	sudoku ".."
 	"../../sat"
 )
 
-var digitRegex = regexp.MustCompile("[0-9]")
-
 func litName(coordinate sudoku.Coordinate, value int) string {
 	return fmt.Sprintf("%d-%d:%d", coordinate.Row(), coordinate.Col(), value)
 }
@@ -21,12 +18,25 @@ func toLiteral(coordinate sudoku.Coordinate, value int) sat.Literal {
 	return sat.NewLiteral(name)
 }
 
+// cellNames returns the names of the variables for every value of every cell on the board.
+func cellNames(board sudoku.Board) []string {
+	names := make([]string, 0)
+	for _, coordinate := range board.AllCoordinates() {
+		for _, value := range board.AllValues() {
+			names = append(names, litName(coordinate, value))
+		}
+	}
+	return names
+}
+
 // fromName parses a variable name back to a coordinate and its value.
+// This is the inverse of litName, and is on the solver's hot path, so it avoids regexes.
 func fromName(name string) (sudoku.Coordinate, int) {
-	digits := digitRegex.FindAllString(name, -1)
-	row, _ := strconv.Atoi(digits[0])
-	col, _ := strconv.Atoi(digits[1])
-	value, _ := strconv.Atoi(digits[2])
+	cell, valueString, _ := strings.Cut(name, ":")
+	rowString, colString, _ := strings.Cut(cell, "-")
+	row, _ := strconv.Atoi(rowString)
+	col, _ := strconv.Atoi(colString)
+	value, _ := strconv.Atoi(valueString)
 	return sudoku.NewCoordinate(row, col), value
 }
 
@@ -32,19 +32,15 @@ func Appears(coordinates []sudoku.Coordinate, values ...int) sat.ConjunctiveForm
 
 // uniqueValues specifies that all coordinates have unique values.
 func uniqueValues(coordinates []sudoku.Coordinate, possibleValues []int) sat.ConjunctiveFormula {
-	clauses := make([]sat.DisjunctiveClause, 0)
-	for i, a := range coordinates {
-		for _, b := range coordinates[i+1:] {
-			for _, value := range possibleValues {
-				// a != value || b != value
-				notA := toLiteral(a, value).Negate()
-				notB := toLiteral(b, value).Negate()
-				clause := sat.NewDisjunctiveClause(notA, notB)
-				clauses = append(clauses, clause)
-			}
+	formula := sat.EmptyConjunctiveFormula()
+	for _, value := range possibleValues {
+		literals := make([]sat.Literal, 0, len(coordinates))
+		for _, coordinate := range coordinates {
+			literals = append(literals, toLiteral(coordinate, value))
 		}
+		formula = formula.And(sat.AtMost(1, literals...).ToFormula())
 	}
-	return sat.NewConjunctiveFormula(clauses)
+	return formula
 }
 
 // Sum sums the values of these literals as if they were true.
@@ -57,19 +53,22 @@ func sum(literals sat.Literals) (sum int) {
 	return sum
 }
 
-// unverified
-
 // SumEquals returns the formula specifying that the sums of the values of the given coordinates equals the desired sum.
+// Every combination of values with the right sum is listed, and one of them must hold.
 func SumEquals(coordinates sudoku.Coordinates, values []int, total int) sat.ConjunctiveFormula {
 	var allCombinations []sat.Literals = allPermutations(coordinates, values)
-	clauses := make([]sat.ConjunctiveClause, 0)
+	combinations := make([]sat.Expression, 0)
 	for _, combination := range allCombinations {
 		if sum(combination) == total {
-			clauses = append(clauses, sat.NewConjunctiveClause(combination...))
+			literals := make([]sat.Expression, 0, len(combination))
+			for _, literal := range combination {
+				literals = append(literals, literal)
+			}
+			combinations = append(combinations, sat.And(literals...))
 		}
 	}
 
-	return sat.NewDisjunctiveFormula(clauses).ToCNF()
+	return sat.ExpressionToCNF(sat.Or(combinations...), sat.PlaistedGreenbaumEncoding)
 }
 
 // allPermutations determines all possible permuations for the specified cells.
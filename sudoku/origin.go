@@ -0,0 +1,122 @@
+package sudoku
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Given is an initial value of a cell.
+type Given struct {
+	Coordinate Coordinate
+	Value      int
+}
+
+func (g Given) String() string {
+	return fmt.Sprintf("given %d at %s", g.Value, cellName(g.Coordinate))
+}
+
+// TaggedConstraint is a constraint, tagged with the rule, clue or given value that produced it.
+// Exactly one of its origins is set.
+type TaggedConstraint struct {
+	Constraint Constraint
+	Rule       Rule
+	Clue       Clue
+	Given      *Given
+}
+
+// TaggedConstraints returns the constraints from the board's rules, clues and initial values, tagged with their
+// origins. Initial values are ordered by coordinate.
+func (b Board) TaggedConstraints() (constraints []TaggedConstraint) {
+	for _, rule := range b.rules {
+		for _, constraint := range rule.Apply(b) {
+			constraints = append(constraints, TaggedConstraint{Constraint: constraint, Rule: rule})
+		}
+	}
+
+	for _, clue := range b.clues {
+		for _, constraint := range clue.Apply(b) {
+			constraints = append(constraints, TaggedConstraint{Constraint: constraint, Clue: clue})
+		}
+	}
+
+	givens := make([]Given, 0, len(b.values))
+	for coordinate, value := range b.values {
+		givens = append(givens, Given{coordinate, value})
+	}
+	sort.Slice(givens, func(i, j int) bool {
+		a, z := givens[i].Coordinate, givens[j].Coordinate
+		return a.row < z.row || (a.row == z.row && a.col < z.col)
+	})
+	for i := range givens {
+		given := &givens[i]
+		constraints = append(constraints, TaggedConstraint{Constraint: NewCellValueConstraint(given.Coordinate, given.Value), Given: given})
+	}
+
+	return constraints
+}
+
+// String describes the constraint in terms of where it came from, like "row 6 uniqueness" or "KillerCage 25 at r3c4".
+func (t TaggedConstraint) String() string {
+	switch {
+	case t.Given != nil:
+		return t.Given.String()
+	case t.Clue != nil:
+		if _, ok := t.Constraint.(UniqueValueConstraint); ok {
+			return fmt.Sprintf("%s uniqueness", t.Clue)
+		}
+		return fmt.Sprint(t.Clue)
+	}
+	if _, ok := t.Rule.(AntiKnightMoveRule); ok {
+		if constraint, ok := t.Constraint.(UniqueValueConstraint); ok {
+			return fmt.Sprintf("anti-knight move %s", cellNames(constraint.Coordinates()))
+		}
+	}
+
+	switch constraint := t.Constraint.(type) {
+	case CellValueConstraint:
+		return fmt.Sprintf("%s has one value", cellName(constraint.Coordinate()))
+	case UniqueValueConstraint:
+		return fmt.Sprintf("%s uniqueness", groupName(constraint.Coordinates()))
+	case ContainsValuesConstraint:
+		return fmt.Sprintf("%s contains %v", groupName(constraint.Coordinates()), constraint.Values())
+	default:
+		return fmt.Sprintf("%T", constraint)
+	}
+}
+
+// cellName names a cell by its row and column, like "r3c4".
+func cellName(coordinate Coordinate) string {
+	return fmt.Sprintf("r%dc%d", coordinate.row, coordinate.col)
+}
+
+// cellNames names each of the given cells.
+func cellNames(coordinates []Coordinate) string {
+	names := make([]string, 0, len(coordinates))
+	for _, coordinate := range coordinates {
+		names = append(names, cellName(coordinate))
+	}
+	return strings.Join(names, " ")
+}
+
+// groupName names a group of cells as a row, column or box, if it is one.
+func groupName(coordinates []Coordinate) string {
+	sameRow, sameCol, sameBox := true, true, true
+	first := coordinates[0]
+	for _, coordinate := range coordinates {
+		sameRow = sameRow && coordinate.row == first.row
+		sameCol = sameCol && coordinate.col == first.col
+		sameBox = sameBox && (coordinate.row-1)/3 == (first.row-1)/3 && (coordinate.col-1)/3 == (first.col-1)/3
+	}
+
+	switch {
+	case sameRow:
+		return fmt.Sprintf("row %d", first.row)
+	case sameCol:
+		return fmt.Sprintf("column %d", first.col)
+	case sameBox:
+		return fmt.Sprintf("box %d", (first.row-1)/3*3+(first.col-1)/3+1)
+	default:
+		return cellNames(coordinates)
+	}
+}
@@ -0,0 +1,94 @@
+package sudoku
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Uniqueness describes how many solutions a board has.
+// Its values count the solutions, capped at two.
+type Uniqueness int
+
+const (
+	// NoSolution means the board can't be solved.
+	NoSolution Uniqueness = iota
+	// UniqueSolution means the board has exactly one solution.
+	UniqueSolution
+	// MultipleSolutions means the board has more than one solution.
+	MultipleSolutions
+)
+
+func (u Uniqueness) String() string {
+	switch u {
+	case NoSolution:
+		return "no solution"
+	case UniqueSolution:
+		return "unique"
+	case MultipleSolutions:
+		return "multiple solutions"
+	default:
+		return fmt.Sprintf("Uniqueness(%d)", int(u))
+	}
+}
+
+// SolutionFinder finds up to limit distinct solutions to a board.
+// Solvers depend on this package, so CheckUnique is given one rather than calling it directly.
+type SolutionFinder func(board Board, limit int) []Board
+
+// UniquenessReport is the result of checking whether a board has a unique solution.
+type UniquenessReport struct {
+	Uniqueness  Uniqueness
+	Solutions   []Board      // Up to two solutions to the board.
+	Differences []Coordinate // The cells whose values differ between the two solutions, if there are multiple.
+}
+
+// CheckUnique checks whether this board has exactly one solution, using the given solver, like conversion.Solutions.
+// If it has several, the report includes two of them and the cells where they differ.
+func (b Board) CheckUnique(solutionFinder SolutionFinder) UniquenessReport {
+	solutions := solutionFinder(b, 2)
+	report := UniquenessReport{Uniqueness: Uniqueness(len(solutions)), Solutions: solutions}
+	if report.Uniqueness != MultipleSolutions {
+		return report
+	}
+
+	first, second := solutions[0], solutions[1]
+	for _, coordinate := range b.AllCoordinates() {
+		a, _ := first.Value(coordinate)
+		z, _ := second.Value(coordinate)
+		if a != z {
+			report.Differences = append(report.Differences, coordinate)
+		}
+	}
+	return report
+}
+
+// String shows the first solution, with the cells that differ in the second solution marked with '*'.
+func (r UniquenessReport) String() string {
+	switch r.Uniqueness {
+	case NoSolution:
+		return "No solution."
+	case UniqueSolution:
+		return "Unique solution:\n" + r.Solutions[0].String()
+	}
+
+	first, second := r.Solutions[0], r.Solutions[1]
+	differs := make(map[Coordinate]bool)
+	lines := make([]string, 0)
+	for _, coordinate := range r.Differences {
+		differs[coordinate] = true
+		a, _ := first.Value(coordinate)
+		z, _ := second.Value(coordinate)
+		lines = append(lines, fmt.Sprintf("%s: %d or %d", cellName(coordinate), a, z))
+	}
+
+	grid := first.render(func(coordinate Coordinate) string {
+		if differs[coordinate] {
+			return "*"
+		}
+		value, _ := first.Value(coordinate)
+		return strconv.Itoa(value)
+	})
+
+	return fmt.Sprintf("Multiple solutions, differing in %d cells:\n%s\n%s", len(r.Differences), grid, strings.Join(lines, "\n"))
+}